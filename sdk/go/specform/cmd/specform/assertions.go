@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+)
+
+// printAssertionReport prints each assertion message, marking failed
+// warnings and info assertions, followed by the aggregate score.
+func printAssertionReport(out io.Writer, report types.AssertionReport) {
+	for _, r := range report.Results {
+		printAssertionResult(out, r, 0)
+	}
+
+	fmt.Fprintf(out, "📊 Score %.2f (threshold %.2f), %d failed, %d warnings\n", report.Score, report.Threshold, report.Failed, report.Warnings)
+}
+
+// printAssertionResult prints a result, the diff of a failed equals
+// assertion and the members of a group result, indented below it.
+func printAssertionResult(out io.Writer, r types.AssertionResult, depth int) {
+	indent := strings.Repeat("  ", depth)
+	switch {
+	case r.Passed || r.Severity == "" || r.Severity == types.SeverityError:
+		fmt.Fprintf(out, "%s%s\n", indent, r.Message)
+	case r.Severity == types.SeverityWarn:
+		fmt.Fprintf(out, "%s⚠️ %s\n", indent, r.Message)
+	default:
+		fmt.Fprintf(out, "%sℹ️ %s\n", indent, r.Message)
+	}
+
+	if !r.Passed && r.Diff != "" {
+		for _, line := range strings.Split(strings.TrimSuffix(r.Diff, "\n"), "\n") {
+			fmt.Fprintf(out, "%s    %s\n", indent, line)
+		}
+	}
+
+	for _, child := range r.Results {
+		printAssertionResult(out, child, depth+1)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	specform "github.com/specform/specform/sdk/go/specform/pkg"
+	"github.com/specform/specform/sdk/go/specform/types"
+)
+
+// BatchConfig holds the flags of a batch render.
+type BatchConfig struct {
+	Path        string // JSONL or CSV file of input rows
+	OutPath     string // JSONL results file, stdout when empty
+	IDField     string // row field used as the row ID
+	Concurrency int
+	Redactor    *specform.Redactor // redacts results before they are written, when set
+}
+
+// RunBatch renders the prepared prompt once per row of the batch file and
+// writes the results as JSONL in input order. The compiled prompt is used to
+// find secret inputs when redacting. Row errors are written to the results and
+// counted; they do not stop the batch.
+func RunBatch(ctx context.Context, prompt *types.CompiledPrompt, prepared *specform.PreparedPrompt, base map[string]string, cfg BatchConfig, opts specform.RenderOptions) error {
+	f, err := os.Open(cfg.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open batch file: %w", err)
+	}
+	defer f.Close()
+
+	out := os.Stdout
+	if cfg.OutPath != "" {
+		out, err = os.Create(cfg.OutPath)
+		if err != nil {
+			return fmt.Errorf("failed to create batch output: %w", err)
+		}
+		defer out.Close()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	rows := make(chan specform.BatchRow)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(rows)
+		readErr <- readBatchRows(ctx, f, cfg, base, rows)
+	}()
+
+	results := prepared.RenderBatch(ctx, rows, &specform.BatchOptions{
+		RenderOptions: opts,
+		Concurrency:   cfg.Concurrency,
+	})
+
+	// Results arrive in completion order, so hold them until every earlier
+	// row has been written
+	enc := json.NewEncoder(out)
+	pending := map[int]specform.BatchResult{}
+	next, total, failed := 0, 0, 0
+	for result := range results {
+		pending[result.Index] = result
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			total++
+			if r.Err != nil {
+				failed++
+			}
+			if cfg.Redactor != nil {
+				r = redactBatchResult(cfg.Redactor, prompt, r)
+			}
+			if err := enc.Encode(r); err != nil {
+				return fmt.Errorf("failed to write batch result: %w", err)
+			}
+		}
+	}
+
+	if err := <-readErr; err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "✅ Rendered %d of %d rows\n", total-failed, total)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d rows failed to render", failed, total)
+	}
+	return nil
+}
+
+// redactBatchResult redacts the inputs, output and error of a batch result.
+func redactBatchResult(redactor *specform.Redactor, prompt *types.CompiledPrompt, r specform.BatchResult) specform.BatchResult {
+	secrets := specform.SecretInputs(prompt, r.Inputs)
+	r.Inputs = redactor.RedactInputs(r.Inputs, secrets)
+	r.Output = redactor.Redact(r.Output, secrets)
+	r.Error = redactor.Redact(r.Error, secrets)
+	return r
+}
+
+// readBatchRows sends a row for every record in a JSONL or CSV batch file.
+// Row inputs are layered over the base inputs. Records that cannot be parsed
+// are sent as rows with Err set, so they are reported with their row number
+// without stopping the batch.
+func readBatchRows(ctx context.Context, r io.Reader, cfg BatchConfig, base map[string]string, rows chan<- specform.BatchRow) error {
+	send := func(index int, fields map[string]string, rowErr error) error {
+		inputs := make(map[string]string, len(base)+len(fields))
+		for k, v := range base {
+			inputs[k] = v
+		}
+		for k, v := range fields {
+			inputs[k] = v
+		}
+
+		id := fields[cfg.IDField]
+		if id == "" {
+			id = strconv.Itoa(index + 1)
+		}
+
+		select {
+		case rows <- specform.BatchRow{Index: index, ID: id, Inputs: inputs, Err: rowErr}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if strings.EqualFold(filepath.Ext(cfg.Path), ".csv") {
+		return readCSVRows(r, send)
+	}
+	return readJSONLRows(r, send)
+}
+
+// rowSender sends the fields of a batch row, or the error that made the row
+// unreadable.
+type rowSender func(index int, fields map[string]string, rowErr error) error
+
+func readJSONLRows(r io.Reader, send rowSender) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	index := 0
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var fields map[string]string
+		raw, err := decodeJSONObject([]byte(text))
+		if err != nil {
+			err = fmt.Errorf("invalid JSON on batch line %d: %w", line, err)
+		} else if fields, err = stringifyInputs(raw); err != nil {
+			err = fmt.Errorf("invalid row on batch line %d: %w", line, err)
+		}
+
+		if err := send(index, fields, err); err != nil {
+			return err
+		}
+		index++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read batch file: %w", err)
+	}
+	return nil
+}
+
+func readCSVRows(r io.Reader, send rowSender) error {
+	reader := csv.NewReader(r)
+	// Field counts are checked per row, so one short row doesn't end the batch
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	for index := 0; ; index++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		var fields map[string]string
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			err = fmt.Errorf("invalid CSV row %d: %w", index+1, err)
+		case err != nil:
+			return fmt.Errorf("failed to read CSV row %d: %w", index+1, err)
+		case len(record) != len(header):
+			err = fmt.Errorf("CSV row %d has %d fields, expected %d", index+1, len(record), len(header))
+		default:
+			fields = make(map[string]string, len(header))
+			for i, key := range header {
+				fields[key] = record[i]
+			}
+		}
+
+		if err := send(index, fields, err); err != nil {
+			return err
+		}
+	}
+}
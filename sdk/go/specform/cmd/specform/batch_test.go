@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	specform "github.com/specform/specform/sdk/go/specform/pkg"
+	"github.com/specform/specform/sdk/go/specform/types"
+	"github.com/stretchr/testify/require"
+)
+
+type sentRow struct {
+	index  int
+	fields map[string]string
+	err    error
+}
+
+func collectRows(t *testing.T, read func(rowSender) error) []sentRow {
+	t.Helper()
+	var rows []sentRow
+	err := read(func(index int, fields map[string]string, rowErr error) error {
+		rows = append(rows, sentRow{index, fields, rowErr})
+		return nil
+	})
+	require.NoError(t, err)
+	return rows
+}
+
+func TestReadJSONLRows(t *testing.T) {
+	input := `{"id": "a", "topic": "webhooks"}
+
+{"id": "b", "topic": 
+{"id": "c", "count": 1000000}
+{"id": "d", "nested": {"x": 1.50}}
+["not", "an", "object"]
+`
+	rows := collectRows(t, func(send rowSender) error {
+		return readJSONLRows(strings.NewReader(input), send)
+	})
+
+	require.Len(t, rows, 5)
+	require.Equal(t, map[string]string{"id": "a", "topic": "webhooks"}, rows[0].fields)
+	require.ErrorContains(t, rows[1].err, "invalid JSON on batch line 3")
+	require.Equal(t, 1, rows[1].index)
+	require.NoError(t, rows[2].err)
+	require.Equal(t, "1000000", rows[2].fields["count"])
+	require.Equal(t, `{"x":1.50}`, rows[3].fields["nested"])
+	require.ErrorContains(t, rows[4].err, "invalid JSON on batch line 6")
+}
+
+func TestReadCSVRows(t *testing.T) {
+	input := "id,topic\na,webhooks\nb\nc,\"polling\" x\"\nd,retries\n"
+	rows := collectRows(t, func(send rowSender) error {
+		return readCSVRows(strings.NewReader(input), send)
+	})
+
+	require.Len(t, rows, 4)
+	require.Equal(t, map[string]string{"id": "a", "topic": "webhooks"}, rows[0].fields)
+	require.EqualError(t, rows[1].err, "CSV row 2 has 1 fields, expected 2")
+	require.ErrorContains(t, rows[2].err, "invalid CSV row 3")
+	require.Equal(t, map[string]string{"id": "d", "topic": "retries"}, rows[3].fields)
+}
+
+func TestRunBatch_OrderAndErrorRows(t *testing.T) {
+	dir := t.TempDir()
+	batchPath := filepath.Join(dir, "rows.jsonl")
+	outPath := filepath.Join(dir, "out.jsonl")
+
+	var lines []string
+	for i := range 30 {
+		switch i {
+		case 7:
+			lines = append(lines, `{"id": "broken"`)
+		case 12:
+			lines = append(lines, `{"id": "r12"}`) // missing topic
+		default:
+			lines = append(lines, `{"topic": "t`+string(rune('a'+i%26))+`"}`)
+		}
+	}
+	require.NoError(t, os.WriteFile(batchPath, []byte(strings.Join(lines, "\n")), 0644))
+
+	prompt := &types.CompiledPrompt{Prompt: "Write about {{topic}}.", Inputs: []string{"topic"}}
+	prepared, err := specform.PreparePrompt(prompt)
+	require.NoError(t, err)
+	err = RunBatch(context.Background(), prompt, prepared, nil, BatchConfig{
+		Path:        batchPath,
+		OutPath:     outPath,
+		IDField:     "id",
+		Concurrency: 4,
+	}, specform.RenderOptions{Strict: true})
+	require.EqualError(t, err, "2 of 30 rows failed to render")
+
+	f, err := os.Open(outPath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var results []specform.BatchResult
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r specform.BatchResult
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+		results = append(results, r)
+	}
+
+	require.Len(t, results, 30)
+	for i, r := range results {
+		switch i {
+		case 7:
+			require.Equal(t, "8", r.ID)
+			require.Contains(t, r.Error, "invalid JSON on batch line 8")
+		case 12:
+			require.Equal(t, "r12", r.ID)
+			require.Contains(t, r.Error, "topic")
+		default:
+			require.Empty(t, r.Error)
+			require.Equal(t, "Write about t"+string(rune('a'+i%26))+".", r.Output)
+		}
+	}
+}
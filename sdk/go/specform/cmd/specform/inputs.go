@@ -1,32 +1,218 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
-func LoadInputs(configPath string, inline []string) (map[string]string, error) {
+// InputSources describes where the render, test and snapshot commands read
+// prompt inputs from. Later sources override earlier ones: the inputs file,
+// then environment variables, then inline --input values.
+type InputSources struct {
+	File      string   // JSON or YAML file with an object of inputs
+	Inline    []string // key=value, key=@path, key=@- for stdin or key=@@value for a literal @value
+	EnvPrefix string   // environment variables with this prefix become inputs
+}
+
+// AddInputFlags registers the shared input flags on a command.
+func AddInputFlags(cmd *cobra.Command, src *InputSources) {
+	cmd.Flags().StringVar(&src.File, "inputs", "", "Path to inputs file (.json, .yaml or .yml)")
+	cmd.Flags().StringArrayVar(&src.Inline, "input", nil, "Inline input as key=value, key=@file or key=@- to read stdin; start the value with @@ for a literal @")
+	cmd.Flags().StringVar(&src.EnvPrefix, "input-env", "", "Read inputs from environment variables with this prefix, e.g. PREFIX_ARTICLE → article")
+}
+
+// LoadInputs reads the inputs from every configured source. stdin is read
+// for an inline value of @-, at most once.
+func LoadInputs(src InputSources, stdin io.Reader) (map[string]string, error) {
 	inputs := map[string]string{}
 
-	if configPath != "" {
-		data, err := os.ReadFile(configPath)
+	if src.File != "" {
+		fileInputs, err := loadInputFile(src.File)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read input file: %w", err)
+			return nil, err
 		}
-		if err := json.Unmarshal(data, &inputs); err != nil {
-			return nil, fmt.Errorf("invalid input JSON: %w", err)
+		for k, v := range fileInputs {
+			inputs[k] = v
+		}
+	}
+
+	if src.EnvPrefix != "" {
+		for k, v := range envInputs(src.EnvPrefix, os.Environ()) {
+			inputs[k] = v
 		}
 	}
 
-	for _, pair := range inline {
+	readStdin := false
+	for _, pair := range src.Inline {
 		parts := strings.SplitN(pair, "=", 2)
 		if len(parts) != 2 {
 			return nil, fmt.Errorf("invalid --input format: %s", pair)
 		}
-		inputs[parts[0]] = parts[1]
+		key, val := parts[0], parts[1]
+
+		switch {
+		// @@ escapes a literal value that starts with @
+		case strings.HasPrefix(val, "@@"):
+			val = val[1:]
+
+		case val == "@-":
+			if readStdin {
+				return nil, fmt.Errorf("only one input can be read from stdin: %s", key)
+			}
+			readStdin = true
+
+			data, err := io.ReadAll(stdin)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read input %s from stdin: %w", key, err)
+			}
+			val = trimFinalNewline(string(data))
+
+		case strings.HasPrefix(val, "@"):
+			data, err := os.ReadFile(val[1:])
+			if err != nil {
+				return nil, fmt.Errorf("failed to read input %s: %w", key, err)
+			}
+			val = trimFinalNewline(string(data))
+		}
+
+		inputs[key] = val
+	}
+
+	return inputs, nil
+}
+
+// loadInputFile reads a JSON or YAML object of inputs.
+func loadInputFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return yamlInputs(data)
+	default:
+		raw, err := decodeJSONObject(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input JSON: %w", err)
+		}
+		return stringifyInputs(raw)
+	}
+}
+
+// yamlInputs converts a YAML mapping to input strings. Scalars are kept
+// exactly as written, so dates and numbers such as 1.50 are not reformatted,
+// and lists or mappings are JSON encoded.
+func yamlInputs(data []byte) (map[string]string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid input YAML: %w", err)
+	}
+	inputs := map[string]string{}
+	if len(doc.Content) == 0 {
+		return inputs, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid input YAML: expected a mapping of input names to values")
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, node := root.Content[i].Value, root.Content[i+1]
+		if node.Kind == yaml.AliasNode {
+			node = node.Alias
+		}
+
+		if node.Kind == yaml.ScalarNode {
+			if node.ShortTag() == "!!null" {
+				inputs[key] = ""
+			} else {
+				inputs[key] = node.Value
+			}
+			continue
+		}
+
+		var val any
+		if err := node.Decode(&val); err != nil {
+			return nil, fmt.Errorf("invalid value for input %s: %w", key, err)
+		}
+		encoded, err := json.Marshal(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for input %s: lists and mappings must be JSON compatible: %w", key, err)
+		}
+		inputs[key] = string(encoded)
+	}
+	return inputs, nil
+}
+
+// decodeJSONObject decodes a JSON object, keeping numbers as json.Number so
+// they are not reformatted or rounded.
+func decodeJSONObject(data []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	raw := map[string]any{}
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unexpected data after the object")
 	}
+	return raw, nil
+}
 
+// stringifyInputs converts values decoded by decodeJSONObject to input
+// strings. Numbers are kept as written and lists or objects are JSON
+// encoded.
+func stringifyInputs(raw map[string]any) (map[string]string, error) {
+	inputs := make(map[string]string, len(raw))
+	for k, v := range raw {
+		switch val := v.(type) {
+		case string:
+			inputs[k] = val
+		case nil:
+			inputs[k] = ""
+		case []any, map[string]any:
+			encoded, err := json.Marshal(val)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for input %s: %w", k, err)
+			}
+			inputs[k] = string(encoded)
+		case json.Number:
+			inputs[k] = val.String()
+		case bool:
+			inputs[k] = fmt.Sprint(val)
+		default:
+			return nil, fmt.Errorf("invalid value for input %s: unsupported type %T", k, val)
+		}
+	}
 	return inputs, nil
 }
+
+// envInputs returns the environment variables that start with prefix, keyed
+// by the rest of the variable name in lower case.
+func envInputs(prefix string, environ []string) map[string]string {
+	inputs := map[string]string{}
+	for _, kv := range environ {
+		name, val, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, prefix) || name == prefix {
+			continue
+		}
+		inputs[strings.ToLower(strings.TrimPrefix(name, prefix))] = val
+	}
+	return inputs
+}
+
+func trimFinalNewline(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return strings.TrimSuffix(s, "\r")
+}
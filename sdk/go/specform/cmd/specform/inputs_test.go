@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadInputs_InlineSources(t *testing.T) {
+	dir := t.TempDir()
+	article := filepath.Join(dir, "article.txt")
+	require.NoError(t, os.WriteFile(article, []byte("Webhooks push events.\n"), 0644))
+
+	inputs, err := LoadInputs(InputSources{Inline: []string{
+		"article=@" + article,
+		"notes=@-",
+		"handle=@@jane",
+		"topic=a=b",
+	}}, strings.NewReader("from stdin\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"article": "Webhooks push events.",
+		"notes":   "from stdin",
+		"handle":  "@jane",
+		"topic":   "a=b",
+	}, inputs)
+
+	_, err = LoadInputs(InputSources{Inline: []string{"a=@-", "b=@-"}}, strings.NewReader("x"))
+	require.ErrorContains(t, err, "only one input can be read from stdin: b")
+
+	_, err = LoadInputs(InputSources{Inline: []string{"a=@" + filepath.Join(dir, "missing.txt")}}, nil)
+	require.ErrorContains(t, err, "failed to read input a")
+
+	_, err = LoadInputs(InputSources{Inline: []string{"novalue"}}, nil)
+	require.ErrorContains(t, err, "invalid --input format")
+}
+
+func TestLoadInputs_Precedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inputs.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"tone": "formal", "topic": "webhooks", "count": 1000000, "id": 9007199254740993, "price": 1.50, "draft": true}`), 0644))
+	t.Setenv("SPECFORM_TEST_TONE", "casual")
+	t.Setenv("SPECFORM_TEST_AUDIENCE", "developers")
+
+	inputs, err := LoadInputs(InputSources{
+		File:      path,
+		EnvPrefix: "SPECFORM_TEST_",
+		Inline:    []string{"audience=managers"},
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"tone":     "casual",
+		"topic":    "webhooks",
+		"count":    "1000000",
+		"id":       "9007199254740993",
+		"price":    "1.50",
+		"draft":    "true",
+		"audience": "managers",
+	}, inputs)
+}
+
+func TestEnvInputs(t *testing.T) {
+	inputs := envInputs("APP_", []string{"APP_TONE=casual", "APP_=ignored", "OTHER=x", "APP_NOTE=a=b"})
+	require.Equal(t, map[string]string{"tone": "casual", "note": "a=b"}, inputs)
+}
+
+func TestLoadInputFile_YAML(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "inputs.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+topic: webhooks
+released: 2024-01-02
+price: 1.50
+empty:
+tags: [a, b]
+limits:
+  retries: 3
+copy: &tone casual
+tone: *tone
+`), 0644))
+
+	inputs, err := loadInputFile(path)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"topic":    "webhooks",
+		"released": "2024-01-02",
+		"price":    "1.50",
+		"empty":    "",
+		"tags":     `["a","b"]`,
+		"limits":   `{"retries":3}`,
+		"copy":     "casual",
+		"tone":     "casual",
+	}, inputs)
+
+	bad := filepath.Join(dir, "bad.yml")
+	require.NoError(t, os.WriteFile(bad, []byte("ports:\n  ? [80, 443]\n  : http\n"), 0644))
+	_, err = loadInputFile(bad)
+	require.ErrorContains(t, err, "invalid value for input ports")
+
+	list := filepath.Join(dir, "list.yaml")
+	require.NoError(t, os.WriteFile(list, []byte("- a\n- b\n"), 0644))
+	_, err = loadInputFile(list)
+	require.ErrorContains(t, err, "expected a mapping")
+}
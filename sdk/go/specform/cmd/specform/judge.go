@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+
+	specform "github.com/specform/specform/sdk/go/specform/pkg"
+	"github.com/spf13/cobra"
+)
+
+// judgeKeyEnv names the environment variable holding the judge API key.
+const judgeKeyEnv = "SPECFORM_JUDGE_API_KEY"
+
+// JudgeFlags holds the judge flags shared by the test and snapshot commands.
+type JudgeFlags struct {
+	URL   string // OpenAI compatible API base URL
+	Model string // judge model, judging is disabled when empty
+}
+
+// AddJudgeFlags registers the shared judge flags on a command.
+func AddJudgeFlags(cmd *cobra.Command, f *JudgeFlags) {
+	cmd.Flags().StringVar(&f.URL, "judge-url", "https://api.openai.com/v1", "OpenAI compatible API used by judge assertions")
+	cmd.Flags().StringVar(&f.Model, "judge-model", "", "Model used by judge assertions (API key from "+judgeKeyEnv+")")
+}
+
+// Provider returns the configured judge, or nil when no model is set.
+func (f JudgeFlags) Provider() specform.JudgeProvider {
+	if f.Model == "" {
+		return nil
+	}
+	return specform.NewOpenAIJudge(f.URL, os.Getenv(judgeKeyEnv), f.Model)
+}
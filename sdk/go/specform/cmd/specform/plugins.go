@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	specform "github.com/specform/specform/sdk/go/specform/pkg"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// pluginPathEnv names the environment variable listing extra plugin
+// directories, separated like PATH.
+const pluginPathEnv = "SPECFORM_PLUGIN_PATH"
+
+// defaultPluginConfig is the project plugin list loaded when it exists in the
+// working directory.
+const defaultPluginConfig = "specform.plugins.yaml"
+
+// PluginFlags holds the plugin flags shared by the test and snapshot
+// commands.
+type PluginFlags struct {
+	ConfigPath string   // JSON or YAML map of assertion type to executable
+	Dirs       []string // searched before SPECFORM_PLUGIN_PATH
+	SearchPath bool     // also search PATH, after every other directory
+}
+
+// AddPluginFlags registers the shared plugin flags on a command.
+func AddPluginFlags(cmd *cobra.Command, f *PluginFlags) {
+	cmd.Flags().StringVar(&f.ConfigPath, "plugins", "", "Project plugin list mapping assertion types to executables (default "+defaultPluginConfig+" when present)")
+	cmd.Flags().StringArrayVar(&f.Dirs, "plugin-dir", nil, "Directory with specform-assert-<name> assertion plugins (repeatable)")
+	cmd.Flags().BoolVar(&f.SearchPath, "plugin-path", false, "Also search PATH for specform-assert-<name> plugins")
+}
+
+// Registry returns a copy of the default assertion registry with the
+// configured and discovered plugins added. Plugins listed in the project
+// config take precedence over discovered ones. Every loaded plugin is
+// reported on out, along with plugins that a built-in assertion shadows.
+func (f PluginFlags) Registry(out io.Writer) (*specform.AssertionRegistry, error) {
+	plugins, err := f.configured()
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := append(append([]string(nil), f.Dirs...), filepath.SplitList(os.Getenv(pluginPathEnv))...)
+	if f.SearchPath {
+		dirs = append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+	}
+	for name, path := range specform.DiscoverPlugins(dirs) {
+		if _, ok := plugins[name]; !ok {
+			plugins[name] = path
+		}
+	}
+
+	registry := specform.DefaultAssertionRegistry().Clone()
+	skipped := registry.RegisterPlugins(plugins)
+
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if slices.Contains(skipped, name) {
+			fmt.Fprintf(out, "⚠️ Plugin %s from %s is shadowed by the built-in assertion and will not run\n", name, plugins[name])
+			continue
+		}
+		fmt.Fprintf(out, "🔌 Loaded plugin %s from %s\n", name, plugins[name])
+	}
+	return registry, nil
+}
+
+// configured loads the project plugin list. The default list is optional,
+// one named with --plugins is not.
+func (f PluginFlags) configured() (map[string]string, error) {
+	path := f.ConfigPath
+	if path == "" {
+		path = defaultPluginConfig
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			return map[string]string{}, nil
+		}
+	}
+	return loadPluginConfig(path)
+}
+
+// loadPluginConfig reads a map of assertion type to plugin executable.
+// Relative paths are resolved against the directory of the config file.
+func loadPluginConfig(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin list: %w", err)
+	}
+
+	var plugins map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &plugins)
+	default:
+		err = json.Unmarshal(data, &plugins)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid plugin list: %w", err)
+	}
+	if plugins == nil {
+		plugins = map[string]string{}
+	}
+
+	for name, exe := range plugins {
+		if name == "" || exe == "" {
+			return nil, fmt.Errorf("plugins need an assertion type and an executable")
+		}
+		if !filepath.IsAbs(exe) {
+			exe = filepath.Join(filepath.Dir(path), exe)
+		}
+		info, err := os.Stat(exe)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find plugin %s: %w", name, err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("plugin %s is a directory: %s", name, exe)
+		}
+		plugins[name] = exe
+	}
+	return plugins, nil
+}
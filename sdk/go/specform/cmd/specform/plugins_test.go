@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	specform "github.com/specform/specform/sdk/go/specform/pkg"
+	"github.com/stretchr/testify/require"
+)
+
+func writePlugin(t *testing.T, path string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\necho '{\"passed\": true}'\n"), 0755))
+}
+
+func TestPluginFlags_Registry(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+
+	project := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(project, "tools"), 0755))
+	writePlugin(t, filepath.Join(project, "tools", "reading-level.sh"))
+	config := filepath.Join(project, "plugins.yaml")
+	require.NoError(t, os.WriteFile(config, []byte("reading-level: tools/reading-level.sh\n"), 0644))
+
+	dir := t.TempDir()
+	writePlugin(t, filepath.Join(dir, specform.PluginPrefix+"reading-level"))
+	writePlugin(t, filepath.Join(dir, specform.PluginPrefix+"tone"))
+
+	onPath := t.TempDir()
+	writePlugin(t, filepath.Join(onPath, specform.PluginPrefix+"from-path"))
+	t.Setenv("PATH", onPath)
+	t.Setenv(pluginPathEnv, "")
+
+	var out bytes.Buffer
+	registry, err := PluginFlags{ConfigPath: config, Dirs: []string{dir}}.Registry(&out)
+	require.NoError(t, err)
+	require.True(t, registry.Has("reading-level"))
+	require.True(t, registry.Has("tone"))
+	require.False(t, registry.Has("from-path"), "PATH is only searched with --plugin-path")
+
+	// The project list wins over discovered plugins
+	require.Equal(t, "🔌 Loaded plugin reading-level from "+filepath.Join(project, "tools", "reading-level.sh")+"\n"+
+		"🔌 Loaded plugin tone from "+filepath.Join(dir, specform.PluginPrefix+"tone")+"\n", out.String())
+
+	registry, err = PluginFlags{SearchPath: true}.Registry(&out)
+	require.NoError(t, err)
+	require.True(t, registry.Has("from-path"))
+
+	// Plugins named after built-ins never run, so they are not reported as loaded
+	shadowed := filepath.Join(dir, specform.PluginPrefix+"contains")
+	writePlugin(t, shadowed)
+	out.Reset()
+	_, err = PluginFlags{Dirs: []string{dir}}.Registry(&out)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "⚠️ Plugin contains from "+shadowed+" is shadowed by the built-in assertion and will not run\n")
+	require.NotContains(t, out.String(), "Loaded plugin contains")
+}
+
+func TestPluginFlags_ConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "plugins.json")
+	require.NoError(t, os.WriteFile(missing, []byte(`{"tone": "./nowhere"}`), 0644))
+
+	_, err := PluginFlags{ConfigPath: missing}.Registry(&bytes.Buffer{})
+	require.ErrorContains(t, err, "failed to find plugin tone")
+
+	_, err = PluginFlags{ConfigPath: filepath.Join(dir, "absent.yaml")}.Registry(&bytes.Buffer{})
+	require.ErrorContains(t, err, "failed to read plugin list")
+
+	// Without --plugins a missing default list is not an error
+	_, err = PluginFlags{}.Registry(&bytes.Buffer{})
+	require.NoError(t, err)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	specform "github.com/specform/specform/sdk/go/specform/pkg"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// redactKeyEnv names the environment variable holding the key used to
+// derive redaction placeholders. Without it the public default key is used.
+const redactKeyEnv = "SPECFORM_REDACT_KEY"
+
+// RedactFlags holds the redaction flags shared by the snapshot, serve and
+// render commands.
+type RedactFlags struct {
+	RulesPath  string // JSON or YAML list of {name, pattern} rules
+	NoDefaults bool   // drop the built-in rules
+	RandomKey  bool   // derive placeholders from a key generated for this run
+}
+
+// AddRedactFlags registers the shared redaction flags on a command.
+func AddRedactFlags(cmd *cobra.Command, f *RedactFlags) {
+	cmd.Flags().StringVar(&f.RulesPath, "redact-rules", "", "Path to extra redaction rules (.json, .yaml or .yml)")
+	cmd.Flags().BoolVar(&f.NoDefaults, "no-default-redaction", false, "Disable the built-in email, API key and card number rules")
+	cmd.Flags().BoolVar(&f.RandomKey, "random-redaction-key", false, "Use a new random placeholder key for this run instead of "+redactKeyEnv+" or the default key")
+}
+
+// Redactor builds the redactor described by the flags.
+func (f RedactFlags) Redactor() (*specform.Redactor, error) {
+	var rules []specform.RedactionRule
+	if !f.NoDefaults {
+		rules = specform.DefaultRedactionRules()
+	}
+
+	if f.RulesPath != "" {
+		extra, err := loadRedactionRules(f.RulesPath)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, extra...)
+	}
+
+	key := []byte(os.Getenv(redactKeyEnv))
+	if f.RandomKey {
+		var err error
+		if key, err = specform.RandomRedactionKey(); err != nil {
+			return nil, err
+		}
+	}
+	return specform.NewRedactor(rules, key)
+}
+
+func loadRedactionRules(path string) ([]specform.RedactionRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction rules: %w", err)
+	}
+
+	var rules []specform.RedactionRule
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &rules)
+	default:
+		err = json.Unmarshal(data, &rules)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid redaction rules: %w", err)
+	}
+
+	for _, rule := range rules {
+		if rule.Name == "" || rule.Pattern == "" {
+			return nil, fmt.Errorf("redaction rules need a name and a pattern")
+		}
+	}
+	return rules, nil
+}
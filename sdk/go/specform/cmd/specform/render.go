@@ -3,7 +3,11 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"runtime"
+	"strings"
+	"text/tabwriter"
 
 	specform "github.com/specform/specform/sdk/go/specform/pkg"
 	"github.com/specform/specform/sdk/go/specform/types"
@@ -12,36 +16,77 @@ import (
 
 func NewRenderCommand() *cobra.Command {
 	var promptPath string
-	var inputsPath string
-	var inlineInputs []string
+	var inputSources InputSources
+	var explain bool
+	var batch BatchConfig
+	var redactOutput bool
+	var redact RedactFlags
 
 	cmd := &cobra.Command{
 		Use:   "render",
 		Short: "Render a prompt using a compiled prompt spec and inputs",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			prompt, err := loadCompiledPrompt(promptPath)
+			prompt, prepared, err := loadPreparedPrompt(promptPath)
 			if err != nil {
 				return fmt.Errorf("failed to load prompt: %w", err)
 			}
 
-			inputs, err := LoadInputs(inputsPath, inlineInputs)
+			inputs, err := LoadInputs(inputSources, os.Stdin)
 			if err != nil {
 				return fmt.Errorf("failed to load inputs: %w", err)
 			}
 
-			rendered, err := specform.RenderPrompt(prompt, inputs, &specform.RenderOptions{Strict: true})
+			opts := &specform.RenderOptions{Strict: true}
+
+			// Without --redact the rendered prompt is printed verbatim, since
+			// it is usually piped straight to a model
+			var redactor *specform.Redactor
+			if redactOutput {
+				redactor, err = redact.Redactor()
+				if err != nil {
+					return fmt.Errorf("failed to load redaction rules: %w", err)
+				}
+				batch.Redactor = redactor
+			}
+			secrets := specform.SecretInputs(prompt, inputs)
+
+			if batch.Path != "" {
+				return RunBatch(cmd.Context(), prompt, prepared, inputs, batch, *opts)
+			}
+
+			if explain {
+				rendered, trace, err := prepared.RenderWithTrace(inputs, opts)
+				if err == nil {
+					fmt.Println(redactor.Redact(rendered, secrets))
+				}
+				if trace != nil {
+					printRenderTrace(os.Stderr, trace)
+				}
+				if err != nil {
+					return fmt.Errorf("failed to render: %w", err)
+				}
+				return nil
+			}
+
+			rendered, err := prepared.Render(inputs, opts)
 			if err != nil {
 				return fmt.Errorf("failed to render: %w", err)
 			}
 
-			fmt.Println(rendered)
+			fmt.Println(redactor.Redact(rendered, secrets))
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVar(&promptPath, "prompt", "", "Path to compiled .prompt.json")
-	cmd.Flags().StringVar(&inputsPath, "inputs", "", "Path to inputs.json")
-	cmd.Flags().StringArrayVar(&inlineInputs, "input", nil, "Inline input as key=value")
+	AddInputFlags(cmd, &inputSources)
+	cmd.Flags().BoolVar(&explain, "explain", false, "Print where each rendered value came from to stderr")
+	cmd.Flags().StringVar(&batch.Path, "batch", "", "Render once per row of a .jsonl or .csv file")
+	cmd.Flags().StringVar(&batch.OutPath, "batch-out", "", "Write batch results as JSONL to this file instead of stdout")
+	cmd.Flags().StringVar(&batch.IDField, "batch-id", "id", "Row field used as the row ID in batch results")
+	cmd.Flags().IntVar(&batch.Concurrency, "concurrency", runtime.NumCPU(), "Number of batch rows rendered at once")
+	cmd.Flags().BoolVar(&redactOutput, "redact", false, "Redact secret inputs and matches of the redaction rules from the output")
+	AddRedactFlags(cmd, &redact)
 
 	_ = cmd.MarkFlagRequired("prompt")
 
@@ -62,3 +107,49 @@ func loadCompiledPrompt(path string) (*types.CompiledPrompt, error) {
 	}
 	return &scenario, nil
 }
+
+// loadPreparedPrompt loads a compiled prompt and parses its template once, so
+// template errors are reported at load time and every render reuses it.
+func loadPreparedPrompt(path string) (*types.CompiledPrompt, *specform.PreparedPrompt, error) {
+	prompt, err := loadCompiledPrompt(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	prepared, err := specform.PreparePrompt(prompt)
+	if err != nil {
+		return nil, nil, err
+	}
+	return prompt, prepared, nil
+}
+
+// printRenderTrace writes a table of the variables in a render trace, their
+// source and the byte spans they produced in the rendered prompt.
+func printRenderTrace(out io.Writer, trace *types.RenderTrace) {
+	fmt.Fprintln(out, "🔎 Render trace:")
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  VARIABLE\tSOURCE\tSPANS")
+	for _, v := range trace.Variables {
+		spans := make([]string, 0, len(v.Spans))
+		for _, span := range v.Spans {
+			spans = append(spans, fmt.Sprintf("[%d:%d]", span.Start, span.End))
+		}
+		if len(spans) == 0 {
+			spans = append(spans, "-")
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", v.Name, v.Source, strings.Join(spans, " "))
+	}
+	w.Flush()
+
+	for _, t := range trace.Truncations {
+		fmt.Fprintf(out, "✂️ Truncated %s (%s): %d → %d tokens\n", t.Input, t.Strategy, t.Tokens, t.Kept)
+	}
+	for _, e := range trace.Escapes {
+		fmt.Fprintf(out, "🛡️ Escaped %s (%s): %s\n", e.Input, e.Policy, e.Detail)
+	}
+	fmt.Fprintf(out, "🔢 Tokens: %d input, %d prompt\n", trace.InputTokens, trace.PromptTokens)
+
+	if len(trace.UnusedInputs) > 0 {
+		fmt.Fprintf(out, "⚠️ Unused inputs: %s\n", strings.Join(trace.UnusedInputs, ", "))
+	}
+}
@@ -5,16 +5,17 @@ import (
 	"fmt"
 	"io/fs"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strings"
 
+	specform "github.com/specform/specform/sdk/go/specform/pkg"
 	"github.com/spf13/cobra"
 )
 
 func NewServeCommand() *cobra.Command {
 	var outputDir string
 	var port int
+	var redact RedactFlags
 	var verbose bool
 
 	cmd := &cobra.Command{
@@ -24,6 +25,11 @@ func NewServeCommand() *cobra.Command {
 			logger := NewLogger(verbose)
 			logger.Info("Starting specform server", "dir", outputDir, "port", port)
 
+			redactor, err := redact.Redactor()
+			if err != nil {
+				return fmt.Errorf("failed to load redaction rules: %w", err)
+			}
+
 			withCORS := func(h http.HandlerFunc) http.HandlerFunc {
 				return func(w http.ResponseWriter, r *http.Request) {
 					logger.Debug("Received request", "method", r.Method, "path", r.URL.Path)
@@ -82,14 +88,16 @@ func NewServeCommand() *cobra.Command {
 				logger.Info("Handling request", "method", r.Method, "path", r.URL.Path)
 				id := strings.TrimPrefix(r.URL.Path, "/prompts/")
 				filePath := filepath.Join(outputDir, id+".prompt.json")
-				data, err := os.ReadFile(filePath)
+				prompt, err := loadCompiledPrompt(filePath)
 				if err != nil {
 					logger.Error("Error reading prompt file", "file", filePath, "error", err)
 					http.Error(w, "Prompt not found", http.StatusNotFound)
 					return
 				}
+
+				// Default values of secret inputs must not leak over HTTP
 				w.Header().Set("Content-Type", "application/json")
-				w.Write(data)
+				json.NewEncoder(w).Encode(redactor.RedactPrompt(prompt))
 				logger.Debug("Serving prompt", "id", id)
 			}))
 
@@ -114,14 +122,24 @@ func NewServeCommand() *cobra.Command {
 				logger.Info("Handling request", "method", r.Method, "path", r.URL.Path)
 				id := strings.TrimPrefix(r.URL.Path, "/snapshots/")
 				filePath := filepath.Join(outputDir, id+".snap.json")
-				data, err := os.ReadFile(filePath)
+				snapshot, err := specform.LoadSnapshot(filePath)
 				if err != nil {
 					logger.Error("Error reading snapshot file", "file", filePath, "error", err)
 					http.Error(w, "Snapshot not found", http.StatusNotFound)
 					return
 				}
+
+				// Snapshots are redacted when saved, but older snapshots or ones
+				// written with fewer rules may still hold secrets
+				secrets := map[string]string{}
+				if prompt, err := loadCompiledPrompt(filepath.Join(outputDir, id+".prompt.json")); err == nil {
+					secrets = specform.SecretInputs(prompt, snapshot.Inputs)
+				}
+				redactor.RedactSnapshot(snapshot, secrets)
+
 				w.Header().Set("Content-Type", "application/json")
-				w.Write(data)
+				json.NewEncoder(w).Encode(snapshot)
+				logger.Debug("Serving snapshot", "id", id)
 			}))
 
 			logger.Info("Serving files", "dir", outputDir, "port", port)
@@ -132,6 +150,7 @@ func NewServeCommand() *cobra.Command {
 
 	cmd.Flags().StringVarP(&outputDir, "dir", "d", ".specform", "Directory to serve from")
 	cmd.Flags().IntVarP(&port, "port", "p", 8080, "Port to serve on")
+	AddRedactFlags(cmd, &redact)
 	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
 	return cmd
 }
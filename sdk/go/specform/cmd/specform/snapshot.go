@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	specform "github.com/specform/specform/sdk/go/specform/pkg"
 	"github.com/specform/specform/sdk/go/specform/types"
@@ -12,11 +13,17 @@ import (
 
 func NewSnapshotCommand() *cobra.Command {
 	var promptPath string
-	var inputsPath string
-	var inlineInputs []string
+	var inputSources InputSources
 	var outputPath string
 	var snapshotDir string
 	var similarityPath string
+	var threshold float64
+	var minScore float64
+	var assertionTimeout time.Duration
+	var parallel int
+	var judge JudgeFlags
+	var plugins PluginFlags
+	var redact RedactFlags
 	var verbose bool
 
 	cmd := &cobra.Command{
@@ -25,7 +32,7 @@ func NewSnapshotCommand() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			logger := NewLogger(verbose)
 
-			logger.Info("Creating snapshot", "promptPath", promptPath, "inputsPath", inputsPath, "outputPath", outputPath, "snapshotDir", snapshotDir)
+			logger.Info("Creating snapshot", "promptPath", promptPath, "inputsPath", inputSources.File, "outputPath", outputPath, "snapshotDir", snapshotDir)
 			compiled, err := loadCompiledPrompt(promptPath)
 
 			if err != nil {
@@ -33,7 +40,13 @@ func NewSnapshotCommand() *cobra.Command {
 				return fmt.Errorf("failed to load prompt: %w", err)
 			}
 
-			inputs, err := LoadInputs(inputsPath, inlineInputs)
+			redactor, err := redact.Redactor()
+			if err != nil {
+				logger.Error("Failed to load redaction rules", "error", err)
+				return fmt.Errorf("failed to load redaction rules: %w", err)
+			}
+
+			inputs, err := LoadInputs(inputSources, os.Stdin)
 			if err != nil {
 				logger.Error("Failed to load inputs", "error", err)
 				return fmt.Errorf("failed to load inputs: %w", err)
@@ -53,20 +66,29 @@ func NewSnapshotCommand() *cobra.Command {
 
 			ctx := &types.AssertionContext{
 				SemanticScores: simScores,
+				Threshold:      threshold,
+				Reference:      compiled.Snapshot,
+				Inputs:         specform.AssertionInputs(compiled, inputs),
+				Secrets:        specform.SecretInputs(compiled, inputs),
+				Judge:          judge.Provider(),
+				Timeout:        assertionTimeout,
+				Concurrency:    parallel,
 			}
 
-			results := specform.RunAssertions(string(output), compiled.Assertions, ctx)
-			passed := true
-			for _, r := range results {
-				logger.Debug("Assertion result", "message", r.Message, "passed", r.Passed)
-				fmt.Printf("%s\n", r.Message)
+			registry, err := plugins.Registry(os.Stderr)
+			if err != nil {
+				logger.Error("Failed to load plugins", "error", err)
+				return fmt.Errorf("failed to load plugins: %w", err)
+			}
 
-				if !r.Passed {
-					passed = false
-				}
+			report := registry.EvaluateContext(cmd.Context(), string(output), compiled.Assertions, ctx, minScore)
+			for _, r := range report.Results {
+				logger.Debug("Assertion result", "message", r.Message, "passed", r.Passed, "severity", r.Severity)
 			}
+			printAssertionReport(os.Stdout, report)
+			results := report.Results
 
-			if !passed {
+			if !report.Passed {
 				logger.Info("Some assertions failed. Snapshot not saved")
 				fmt.Println("❌ Some assertions failed. Snapshot not saved.")
 				os.Exit(1)
@@ -78,7 +100,9 @@ func NewSnapshotCommand() *cobra.Command {
 			}
 
 			snapshotPath := filepath.Join(snapshotDir, compiled.ID+".snap.json")
-			err = specform.SaveSnapshot(snapshotPath, compiled, string(output), results, inputs)
+			err = specform.SaveSnapshotWithOptions(snapshotPath, compiled, string(output), results, inputs, &specform.SnapshotOptions{
+				Redactor: redactor,
+			})
 			if err != nil {
 				logger.Error("Failed to save snapshot", "error", err)
 				return fmt.Errorf("failed to save snapshot: %w", err)
@@ -91,11 +115,17 @@ func NewSnapshotCommand() *cobra.Command {
 	}
 
 	cmd.Flags().StringVar(&promptPath, "prompt", "", "Path to compiled .prompt.json")
-	cmd.Flags().StringVar(&inputsPath, "inputs", "", "Path to inputs.json")
-	cmd.Flags().StringArrayVar(&inlineInputs, "input", nil, "Inline input as key=value")
+	AddInputFlags(cmd, &inputSources)
 	cmd.Flags().StringVar(&outputPath, "output", "", "Path to LLM output.txt")
 	cmd.Flags().StringVar(&snapshotDir, "out", "snapshots", "Directory to save snapshots")
 	cmd.Flags().StringVar(&similarityPath, "similarity", "", "Optional similarity score JSON file")
+	cmd.Flags().Float64Var(&threshold, "threshold", 0, "Semantic similarity threshold (default 0.85)")
+	cmd.Flags().Float64Var(&minScore, "min-score", 0, "Minimum weighted assertion score (0-1) to save; failed error assertions always fail")
+	cmd.Flags().DurationVar(&assertionTimeout, "assertion-timeout", 0, "Time limit for each assertion, e.g. 30s (default no limit)")
+	cmd.Flags().IntVar(&parallel, "parallel", 1, "Number of assertions to evaluate at once")
+	AddJudgeFlags(cmd, &judge)
+	AddPluginFlags(cmd, &plugins)
+	AddRedactFlags(cmd, &redact)
 	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
 
 	_ = cmd.MarkFlagRequired("prompt")
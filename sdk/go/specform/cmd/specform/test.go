@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	specform "github.com/specform/specform/sdk/go/specform/pkg"
 	"github.com/specform/specform/sdk/go/specform/types"
@@ -11,10 +12,15 @@ import (
 
 func NewTestCommand() *cobra.Command {
 	var promptPath string
-	var inputsPath string
-	var inlineInputs []string
+	var inputSources InputSources
 	var outputPath string
 	var similarityPath string
+	var threshold float64
+	var minScore float64
+	var assertionTimeout time.Duration
+	var parallel int
+	var judge JudgeFlags
+	var plugins PluginFlags
 
 	cmd := &cobra.Command{
 		Use:   "test",
@@ -25,7 +31,7 @@ func NewTestCommand() *cobra.Command {
 				return fmt.Errorf("failed to load prompt: %w", err)
 			}
 
-			_, err = LoadInputs(inputsPath, inlineInputs)
+			inputs, err := LoadInputs(inputSources, os.Stdin)
 			if err != nil {
 				return fmt.Errorf("failed to load inputs: %w", err)
 			}
@@ -42,32 +48,49 @@ func NewTestCommand() *cobra.Command {
 
 			ctx := &types.AssertionContext{
 				SemanticScores: simScores,
+				Threshold:      threshold,
+				Reference:      compiled.Snapshot,
+				Inputs:         specform.AssertionInputs(compiled, inputs),
+				Secrets:        specform.SecretInputs(compiled, inputs),
+				Judge:          judge.Provider(),
+				Timeout:        assertionTimeout,
+				Concurrency:    parallel,
 			}
 
-			results := specform.RunAssertions(string(output), compiled.Assertions, ctx)
-			passed := true
-			for _, r := range results {
-				fmt.Printf("%s\n", r.Message)
-				if !r.Passed {
-					passed = false
-				}
+			registry, err := plugins.Registry(os.Stderr)
+			if err != nil {
+				return fmt.Errorf("failed to load plugins: %w", err)
 			}
 
-			if passed {
-				fmt.Println("✅ All assertions passed!")
-			} else {
+			report := registry.EvaluateContext(cmd.Context(), string(output), compiled.Assertions, ctx, minScore)
+			printAssertionReport(os.Stdout, report)
+
+			switch {
+			case !report.Passed && report.Failed == 0:
+				fmt.Printf("❌ Score %.2f is below the minimum of %.2f\n", report.Score, report.Threshold)
+				os.Exit(1)
+			case !report.Passed:
 				fmt.Println("❌ Some assertions failed")
 				os.Exit(1)
+			case report.Warnings > 0:
+				fmt.Println("✅ Assertions passed with warnings")
+			default:
+				fmt.Println("✅ All assertions passed!")
 			}
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVar(&promptPath, "prompt", "", "Path to compiled .prompt.json")
-	cmd.Flags().StringVar(&inputsPath, "inputs", "", "Path to inputs.json")
-	cmd.Flags().StringArrayVar(&inlineInputs, "input", nil, "Inline input as key=value")
+	AddInputFlags(cmd, &inputSources)
 	cmd.Flags().StringVar(&outputPath, "output", "", "Path to LLM output.txt")
 	cmd.Flags().StringVar(&similarityPath, "similarity", "", "Optional similarity score JSON file")
+	cmd.Flags().Float64Var(&threshold, "threshold", 0, "Semantic similarity threshold (default 0.85)")
+	cmd.Flags().Float64Var(&minScore, "min-score", 0, "Minimum weighted assertion score (0-1) to pass; failed error assertions always fail")
+	cmd.Flags().DurationVar(&assertionTimeout, "assertion-timeout", 0, "Time limit for each assertion, e.g. 30s (default no limit)")
+	cmd.Flags().IntVar(&parallel, "parallel", 1, "Number of assertions to evaluate at once")
+	AddJudgeFlags(cmd, &judge)
+	AddPluginFlags(cmd, &plugins)
 	_ = cmd.MarkFlagRequired("prompt")
 	_ = cmd.MarkFlagRequired("output")
 
@@ -3,18 +3,48 @@ package internal
 import (
 	"bufio"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/specform/specform/sdk/go/specform/types"
 )
 
+// assertionNode is an assertion being parsed along with its indentation,
+// so that indented items can be added to any-of and all-of groups.
+type assertionNode struct {
+	indent    int
+	assertion types.Assertion
+	children  []*assertionNode
+}
+
+// ParseAssertionsBlock parses `- type: value` lines. Indented lines such as
+// `weight: 2`, `severity: warn` or `timeout: 30s` below an assertion set its
+// options, and indented items below `- any-of:` or `- all-of:` belong to
+// that group.
 func ParseAssertionsBlock(content string) ([]types.Assertion, error) {
 	scanner := bufio.NewScanner(strings.NewReader(content))
-	var out []types.Assertion
+	root := &assertionNode{indent: -1}
+	stack := []*assertionNode{root}
 
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		indent := len(raw) - len(strings.TrimLeft(raw, " \t"))
+
 		if !strings.HasPrefix(line, "-") {
+			// Options belong to the innermost assertion they are indented under
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].indent < indent {
+					if err := parseAssertionOption(&stack[i].assertion, line); err != nil {
+						return nil, err
+					}
+					break
+				}
+			}
 			continue
 		}
 
@@ -28,15 +58,129 @@ func ParseAssertionsBlock(content string) ([]types.Assertion, error) {
 		typName := strings.TrimSpace(parts[0])
 		val := strings.TrimSpace(parts[1])
 
-		out = append(out, types.Assertion{
-			Type:  typName,
-			Value: strings.Trim(val, "\""),
-		})
+		// Only strip a matching pair of quotes, so values such as
+		// `$.sentiment "positive"` keep their inner quotes
+		if len(val) >= 2 && strings.HasPrefix(val, "\"") && strings.HasSuffix(val, "\"") {
+			val = val[1 : len(val)-1]
+		}
+
+		// Items are nested only under a less indented group
+		for len(stack) > 1 {
+			top := stack[len(stack)-1]
+			if top.indent < indent && isAssertionGroup(top.assertion.Type) {
+				break
+			}
+			stack = stack[:len(stack)-1]
+		}
+
+		node := &assertionNode{indent: indent, assertion: types.Assertion{Type: typName, Value: val}}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, node)
+		stack = append(stack, node)
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("failed to parse assertions block: %w", err)
 	}
 
+	return buildAssertions(root.children)
+}
+
+// buildAssertions converts parsed nodes into assertions, checking that every
+// group has members.
+func buildAssertions(nodes []*assertionNode) ([]types.Assertion, error) {
+	var out []types.Assertion
+	for _, n := range nodes {
+		a := n.assertion
+		if isAssertionGroup(a.Type) {
+			if len(n.children) == 0 {
+				return nil, fmt.Errorf("assertion group %s has no assertions", a.Type)
+			}
+			children, err := buildAssertions(n.children)
+			if err != nil {
+				return nil, err
+			}
+			a.Assertions = children
+		}
+		out = append(out, a)
+	}
 	return out, nil
 }
+
+// isAssertionGroup reports whether typ is an any-of or all-of group,
+// possibly negated.
+func isAssertionGroup(typ string) bool {
+	typ = strings.TrimPrefix(typ, types.NegatePrefix)
+	return typ == types.AssertionAnyOf || typ == types.AssertionAllOf
+}
+
+// parseAssertionOption applies a `weight:`, `severity:` or `timeout:` line
+// to an assertion. Other lines are ignored.
+func parseAssertionOption(a *types.Assertion, line string) error {
+	key, val, ok := strings.Cut(line, ":")
+	if !ok {
+		return nil
+	}
+	val = strings.Trim(strings.TrimSpace(val), "\"")
+
+	switch strings.TrimSpace(key) {
+	case "weight":
+		weight, err := strconv.ParseFloat(val, 64)
+		if err != nil || weight <= 0 {
+			return fmt.Errorf("invalid weight %q for assertion %s", val, a.Type)
+		}
+		a.Weight = weight
+	case "severity":
+		switch val {
+		case types.SeverityError, types.SeverityWarn, types.SeverityInfo:
+			a.Severity = val
+		default:
+			return fmt.Errorf("invalid severity %q for assertion %s", val, a.Type)
+		}
+	case "timeout":
+		timeout, err := time.ParseDuration(val)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid timeout %q for assertion %s", val, a.Type)
+		}
+		a.Timeout = timeout
+	}
+	return nil
+}
+
+// ValidateAssertionValues checks that input references in assertion values
+// parse and only call registered template functions, that expr assertions
+// type-check and that regexes compile.
+func ValidateAssertionValues(assertions []types.Assertion, registry *FuncRegistry) error {
+	for _, a := range assertions {
+		if strings.Contains(a.Value, "{{") {
+			var err error
+			if typ := strings.TrimPrefix(a.Type, types.NegatePrefix); IsRegexAssertion(typ) {
+				_, err = ParseRegexTemplate("assertion", typ, a.Value, registry)
+			} else {
+				_, err = ParseTemplate("assertion", a.Value, registry)
+			}
+			if err != nil {
+				return fmt.Errorf("invalid value for assertion %s: %w", a.Type, err)
+			}
+		} else {
+			switch typ := strings.TrimPrefix(a.Type, types.NegatePrefix); typ {
+			case "expr":
+				if _, err := CompileExpr(a.Value); err != nil {
+					return fmt.Errorf("invalid expression for assertion %s: %w", a.Type, err)
+				}
+			case "matches", "match-count", "capture-equals":
+				if err := ValidateRegexAssertion(typ, a.Value); err != nil {
+					return fmt.Errorf("invalid value for assertion %s: %w", a.Type, err)
+				}
+			case "valid-code", "code-block-lang", "code-block-count":
+				if err := ValidateCodeAssertion(typ, a.Value); err != nil {
+					return fmt.Errorf("invalid value for assertion %s: %w", a.Type, err)
+				}
+			}
+		}
+		if err := ValidateAssertionValues(a.Assertions, registry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
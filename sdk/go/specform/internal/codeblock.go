@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// codeLanguages maps the fence info strings accepted by code block
+// assertions to the language they are validated as.
+var codeLanguages = map[string]string{
+	"go":     "go",
+	"golang": "go",
+	"json":   "json",
+	"yaml":   "yaml",
+	"yml":    "yaml",
+}
+
+// CodeLanguage returns the canonical name of a fence language tag, e.g.
+// "go" for "golang" or "yaml" for "yml", or the lower-cased tag for
+// languages without a validator.
+func CodeLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if lang, ok := codeLanguages[tag]; ok {
+		return lang
+	}
+	return tag
+}
+
+// ValidateCodeAssertion checks the value of a valid-code or
+// code-block-count assertion.
+func ValidateCodeAssertion(typ, value string) error {
+	value = strings.TrimSpace(value)
+	switch typ {
+	case "valid-code":
+		if _, ok := codeLanguages[strings.ToLower(value)]; !ok {
+			return fmt.Errorf("unsupported language %q, expected go, json or yaml", value)
+		}
+	case "code-block-lang":
+		if value == "" {
+			return fmt.Errorf("missing language tag")
+		}
+	case "code-block-count":
+		if value == "" {
+			return fmt.Errorf("missing count, e.g. \"== 1\" or \"go >= 2\"")
+		}
+	}
+	return nil
+}
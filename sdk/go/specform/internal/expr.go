@@ -0,0 +1,673 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Expressions for the expr assertion use Go expression syntax, e.g.
+// `len(words(output)) < 100 && contains(lower(output), inputs.product)`.
+// They can only read the output, reference and inputs and call the
+// functions in exprFuncs, so an expression cannot loop, allocate without
+// bound or reach outside of the sandbox.
+
+// maxExprLength is the longest expression source accepted.
+const maxExprLength = 4096
+
+// exprType is the static type of an expression.
+type exprType int
+
+const (
+	typeBool exprType = iota + 1
+	typeNumber
+	typeString
+	typeList   // list of strings
+	typeInputs // the inputs map
+)
+
+func (t exprType) String() string {
+	switch t {
+	case typeBool:
+		return "bool"
+	case typeNumber:
+		return "number"
+	case typeString:
+		return "string"
+	case typeList:
+		return "list"
+	case typeInputs:
+		return "inputs"
+	}
+	return "invalid"
+}
+
+// exprSignature is one accepted set of argument types of a function.
+type exprSignature struct {
+	params []exprType
+	result exprType
+}
+
+// exprFunc is a function that expressions can call. Arguments are bool,
+// float64, string or []string values matching one of the signatures.
+type exprFunc struct {
+	signatures []exprSignature
+	call       func(args []any) (any, error)
+}
+
+func sig(result exprType, params ...exprType) exprSignature {
+	return exprSignature{params: params, result: result}
+}
+
+var exprFuncs = map[string]exprFunc{
+	"len": {
+		signatures: []exprSignature{sig(typeNumber, typeString), sig(typeNumber, typeList)},
+		call: func(args []any) (any, error) {
+			if s, ok := args[0].(string); ok {
+				return float64(utf8.RuneCountInString(s)), nil
+			}
+			return float64(len(args[0].([]string))), nil
+		},
+	},
+	"lower": stringFunc(strings.ToLower),
+	"upper": stringFunc(strings.ToUpper),
+	"trim":  stringFunc(strings.TrimSpace),
+	"words": {
+		signatures: []exprSignature{sig(typeList, typeString)},
+		call: func(args []any) (any, error) {
+			return nonNil(Words(args[0].(string))), nil
+		},
+	},
+	"lines": {
+		signatures: []exprSignature{sig(typeList, typeString)},
+		call: func(args []any) (any, error) {
+			return nonNil(Lines(args[0].(string))), nil
+		},
+	},
+	"sentences": {
+		signatures: []exprSignature{sig(typeList, typeString)},
+		call: func(args []any) (any, error) {
+			return nonNil(Sentences(args[0].(string))), nil
+		},
+	},
+	"split": {
+		signatures: []exprSignature{sig(typeList, typeString, typeString)},
+		call: func(args []any) (any, error) {
+			return strings.Split(args[0].(string), args[1].(string)), nil
+		},
+	},
+	"join": {
+		signatures: []exprSignature{sig(typeString, typeList, typeString)},
+		call: func(args []any) (any, error) {
+			return strings.Join(args[0].([]string), args[1].(string)), nil
+		},
+	},
+	"contains": {
+		signatures: []exprSignature{sig(typeBool, typeString, typeString), sig(typeBool, typeList, typeString)},
+		call: func(args []any) (any, error) {
+			if s, ok := args[0].(string); ok {
+				return strings.Contains(s, args[1].(string)), nil
+			}
+			for _, item := range args[0].([]string) {
+				if item == args[1].(string) {
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+	},
+	"startsWith": {
+		signatures: []exprSignature{sig(typeBool, typeString, typeString)},
+		call: func(args []any) (any, error) {
+			return strings.HasPrefix(args[0].(string), args[1].(string)), nil
+		},
+	},
+	"endsWith": {
+		signatures: []exprSignature{sig(typeBool, typeString, typeString)},
+		call: func(args []any) (any, error) {
+			return strings.HasSuffix(args[0].(string), args[1].(string)), nil
+		},
+	},
+	"count": {
+		signatures: []exprSignature{sig(typeNumber, typeString, typeString)},
+		call: func(args []any) (any, error) {
+			return float64(strings.Count(args[0].(string), args[1].(string))), nil
+		},
+	},
+	"matches": {
+		signatures: []exprSignature{sig(typeBool, typeString, typeString)},
+		call: func(args []any) (any, error) {
+			re, err := regexp.Compile(args[1].(string))
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex: %w", err)
+			}
+			return re.MatchString(args[0].(string)), nil
+		},
+	},
+	"number": {
+		signatures: []exprSignature{sig(typeNumber, typeString)},
+		call: func(args []any) (any, error) {
+			n, err := strconv.ParseFloat(strings.TrimSpace(args[0].(string)), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a number", args[0])
+			}
+			return n, nil
+		},
+	},
+	"abs": {
+		signatures: []exprSignature{sig(typeNumber, typeNumber)},
+		call: func(args []any) (any, error) {
+			return math.Abs(args[0].(float64)), nil
+		},
+	},
+}
+
+func stringFunc(fn func(string) string) exprFunc {
+	return exprFunc{
+		signatures: []exprSignature{sig(typeString, typeString)},
+		call: func(args []any) (any, error) {
+			return fn(args[0].(string)), nil
+		},
+	}
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// Expr is a parsed and type-checked expression. It is safe for concurrent
+// use.
+type Expr struct {
+	source string
+	root   ast.Expr
+	fset   *token.FileSet
+	regexs map[ast.Expr]*regexp.Regexp // precompiled literal patterns of matches calls
+}
+
+// CompileExpr parses an expression and checks that it is a well typed
+// boolean expression.
+func CompileExpr(source string) (*Expr, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return nil, fmt.Errorf("empty expression")
+	}
+	if len(source) > maxExprLength {
+		return nil, fmt.Errorf("expression is longer than %d bytes", maxExprLength)
+	}
+
+	fset := token.NewFileSet()
+	root, err := parser.ParseExprFrom(fset, "", source, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %w", err)
+	}
+
+	e := &Expr{
+		source: source,
+		root:   root,
+		fset:   fset,
+		regexs: map[ast.Expr]*regexp.Regexp{},
+	}
+	t, err := e.check(root)
+	if err != nil {
+		return nil, err
+	}
+	if t != typeBool {
+		return nil, fmt.Errorf("expression must be a bool, got %s", t)
+	}
+	return e, nil
+}
+
+// String returns the expression source.
+func (e *Expr) String() string {
+	return e.source
+}
+
+// errorf returns an error prefixed with the column of node.
+func (e *Expr) errorf(node ast.Node, format string, args ...any) error {
+	col := e.fset.Position(node.Pos()).Column
+	return fmt.Errorf("column %d: %s", col, fmt.Sprintf(format, args...))
+}
+
+// check returns the type of node.
+func (e *Expr) check(node ast.Expr) (exprType, error) {
+	switch n := node.(type) {
+	case *ast.ParenExpr:
+		return e.check(n.X)
+
+	case *ast.BasicLit:
+		switch n.Kind {
+		case token.INT, token.FLOAT:
+			if _, err := strconv.ParseFloat(n.Value, 64); err != nil {
+				return 0, e.errorf(n, "invalid number %s", n.Value)
+			}
+			return typeNumber, nil
+		case token.STRING:
+			if _, err := strconv.Unquote(n.Value); err != nil {
+				return 0, e.errorf(n, "invalid string %s", n.Value)
+			}
+			return typeString, nil
+		}
+		return 0, e.errorf(n, "unsupported literal %s", n.Value)
+
+	case *ast.Ident:
+		switch n.Name {
+		case "output", "reference":
+			return typeString, nil
+		case "inputs":
+			return typeInputs, nil
+		case "true", "false":
+			return typeBool, nil
+		}
+		return 0, e.errorf(n, "unknown identifier %s", n.Name)
+
+	case *ast.SelectorExpr:
+		x, err := e.check(n.X)
+		if err != nil {
+			return 0, err
+		}
+		if x != typeInputs {
+			return 0, e.errorf(n, "cannot select .%s from %s", n.Sel.Name, x)
+		}
+		return typeString, nil
+
+	case *ast.IndexExpr:
+		x, err := e.check(n.X)
+		if err != nil {
+			return 0, err
+		}
+		idx, err := e.check(n.Index)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case x == typeInputs && idx == typeString:
+			return typeString, nil
+		case x == typeList && idx == typeNumber:
+			return typeString, nil
+		}
+		return 0, e.errorf(n, "cannot index %s with %s", x, idx)
+
+	case *ast.UnaryExpr:
+		x, err := e.check(n.X)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case n.Op == token.NOT && x == typeBool:
+			return typeBool, nil
+		case n.Op == token.SUB && x == typeNumber:
+			return typeNumber, nil
+		}
+		return 0, e.errorf(n, "operator %s not defined on %s", n.Op, x)
+
+	case *ast.BinaryExpr:
+		return e.checkBinary(n)
+
+	case *ast.CallExpr:
+		return e.checkCall(n)
+	}
+	return 0, e.errorf(node, "unsupported expression")
+}
+
+func (e *Expr) checkBinary(n *ast.BinaryExpr) (exprType, error) {
+	x, err := e.check(n.X)
+	if err != nil {
+		return 0, err
+	}
+	y, err := e.check(n.Y)
+	if err != nil {
+		return 0, err
+	}
+	if x != y {
+		return 0, e.errorf(n, "mismatched types %s %s %s", x, n.Op, y)
+	}
+
+	switch n.Op {
+	case token.LAND, token.LOR:
+		if x == typeBool {
+			return typeBool, nil
+		}
+	case token.EQL, token.NEQ:
+		if x == typeBool || x == typeNumber || x == typeString {
+			return typeBool, nil
+		}
+	case token.LSS, token.LEQ, token.GTR, token.GEQ:
+		if x == typeNumber || x == typeString {
+			return typeBool, nil
+		}
+	case token.ADD:
+		if x == typeNumber || x == typeString {
+			return x, nil
+		}
+	case token.SUB, token.MUL, token.QUO, token.REM:
+		if x == typeNumber {
+			return typeNumber, nil
+		}
+	}
+	return 0, e.errorf(n, "operator %s not defined on %s", n.Op, x)
+}
+
+func (e *Expr) checkCall(n *ast.CallExpr) (exprType, error) {
+	ident, ok := n.Fun.(*ast.Ident)
+	if !ok {
+		return 0, e.errorf(n, "only built-in functions can be called")
+	}
+	fn, ok := exprFuncs[ident.Name]
+	if !ok {
+		return 0, e.errorf(n, "unknown function %s", ident.Name)
+	}
+	if n.Ellipsis.IsValid() {
+		return 0, e.errorf(n, "unexpected ... in call to %s", ident.Name)
+	}
+
+	args := make([]exprType, len(n.Args))
+	for i, arg := range n.Args {
+		t, err := e.check(arg)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = t
+	}
+
+	for _, s := range fn.signatures {
+		if equalTypes(s.params, args) {
+			// Literal patterns are compiled, and checked, once
+			if ident.Name == "matches" {
+				if lit, ok := n.Args[1].(*ast.BasicLit); ok {
+					pattern, _ := strconv.Unquote(lit.Value)
+					re, err := regexp.Compile(pattern)
+					if err != nil {
+						return 0, e.errorf(lit, "invalid regex: %s", err)
+					}
+					e.regexs[n] = re
+				}
+			}
+			return s.result, nil
+		}
+	}
+
+	names := make([]string, len(args))
+	for i, t := range args {
+		names[i] = t.String()
+	}
+	return 0, e.errorf(n, "cannot call %s with (%s)", ident.Name, strings.Join(names, ", "))
+}
+
+func equalTypes(a, b []exprType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ExprEnv holds the values an expression can read.
+type ExprEnv struct {
+	Output    string
+	Reference string
+	Inputs    map[string]string
+}
+
+// ExprLimits bounds the work an expression may do.
+type ExprLimits struct {
+	MaxSteps int // evaluated nodes
+	MaxSize  int // bytes of any string or list the expression builds
+}
+
+// DefaultExprLimits are used when limits are zero.
+var DefaultExprLimits = ExprLimits{MaxSteps: 100_000, MaxSize: 1 << 20}
+
+// exprEval is the state of one evaluation.
+type exprEval struct {
+	*Expr
+	ctx    context.Context
+	env    ExprEnv
+	limits ExprLimits
+	steps  int
+}
+
+// Eval evaluates the expression. It stops with an error when a limit is
+// exceeded or ctx is cancelled.
+func (e *Expr) Eval(ctx context.Context, env ExprEnv, limits ExprLimits) (bool, error) {
+	if limits.MaxSteps <= 0 {
+		limits.MaxSteps = DefaultExprLimits.MaxSteps
+	}
+	if limits.MaxSize <= 0 {
+		limits.MaxSize = DefaultExprLimits.MaxSize
+	}
+
+	ev := &exprEval{Expr: e, ctx: ctx, env: env, limits: limits}
+	v, err := ev.eval(e.root)
+	if err != nil {
+		return false, err
+	}
+	return v.(bool), nil
+}
+
+func (ev *exprEval) eval(node ast.Expr) (any, error) {
+	ev.steps++
+	if ev.steps > ev.limits.MaxSteps {
+		return nil, fmt.Errorf("expression exceeded %d steps", ev.limits.MaxSteps)
+	}
+	if ev.steps%256 == 0 {
+		if err := ev.ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	v, err := ev.evalNode(node)
+	if err != nil {
+		return nil, err
+	}
+	if err := ev.checkSize(node, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// checkSize fails when a computed string or list is larger than allowed.
+// The output, reference and inputs themselves are not limited.
+func (ev *exprEval) checkSize(node ast.Expr, v any) error {
+	switch node.(type) {
+	case *ast.Ident, *ast.SelectorExpr, *ast.IndexExpr, *ast.ParenExpr:
+		return nil
+	}
+
+	size := 0
+	switch v := v.(type) {
+	case string:
+		size = len(v)
+	case []string:
+		for _, s := range v {
+			size += len(s) + 16
+		}
+	}
+	if size > ev.limits.MaxSize {
+		return ev.errorf(node, "value larger than %d bytes", ev.limits.MaxSize)
+	}
+	return nil
+}
+
+func (ev *exprEval) evalNode(node ast.Expr) (any, error) {
+	switch n := node.(type) {
+	case *ast.ParenExpr:
+		return ev.eval(n.X)
+
+	case *ast.BasicLit:
+		if n.Kind == token.STRING {
+			return strconv.Unquote(n.Value)
+		}
+		return strconv.ParseFloat(n.Value, 64)
+
+	case *ast.Ident:
+		switch n.Name {
+		case "output":
+			return ev.env.Output, nil
+		case "reference":
+			return ev.env.Reference, nil
+		case "inputs":
+			return ev.env.Inputs, nil
+		}
+		return n.Name == "true", nil
+
+	case *ast.SelectorExpr:
+		return ev.input(n, n.Sel.Name)
+
+	case *ast.IndexExpr:
+		x, err := ev.eval(n.X)
+		if err != nil {
+			return nil, err
+		}
+		idx, err := ev.eval(n.Index)
+		if err != nil {
+			return nil, err
+		}
+		if key, ok := idx.(string); ok {
+			return ev.input(n, key)
+		}
+		list, i := x.([]string), idx.(float64)
+		if i < 0 {
+			i += float64(len(list))
+		}
+		if i != math.Trunc(i) || i < 0 || int(i) >= len(list) {
+			return nil, ev.errorf(n, "index %s out of range for list of length %d", strconv.FormatFloat(idx.(float64), 'f', -1, 64), len(list))
+		}
+		return list[int(i)], nil
+
+	case *ast.UnaryExpr:
+		x, err := ev.eval(n.X)
+		if err != nil {
+			return nil, err
+		}
+		if n.Op == token.NOT {
+			return !x.(bool), nil
+		}
+		return -x.(float64), nil
+
+	case *ast.BinaryExpr:
+		return ev.evalBinary(n)
+
+	case *ast.CallExpr:
+		return ev.evalCall(n)
+	}
+	return nil, ev.errorf(node, "unsupported expression")
+}
+
+func (ev *exprEval) input(node ast.Node, name string) (any, error) {
+	v, ok := ev.env.Inputs[name]
+	if !ok {
+		return nil, ev.errorf(node, "input %s has no value", name)
+	}
+	return v, nil
+}
+
+func (ev *exprEval) evalBinary(n *ast.BinaryExpr) (any, error) {
+	x, err := ev.eval(n.X)
+	if err != nil {
+		return nil, err
+	}
+
+	// && and || short-circuit
+	switch n.Op {
+	case token.LAND:
+		if !x.(bool) {
+			return false, nil
+		}
+		return ev.eval(n.Y)
+	case token.LOR:
+		if x.(bool) {
+			return true, nil
+		}
+		return ev.eval(n.Y)
+	}
+
+	y, err := ev.eval(n.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.Op {
+	case token.EQL:
+		return x == y, nil
+	case token.NEQ:
+		return x != y, nil
+	}
+
+	if xs, ok := x.(string); ok {
+		ys := y.(string)
+		switch n.Op {
+		case token.LSS:
+			return xs < ys, nil
+		case token.LEQ:
+			return xs <= ys, nil
+		case token.GTR:
+			return xs > ys, nil
+		case token.GEQ:
+			return xs >= ys, nil
+		}
+		return xs + ys, nil
+	}
+
+	xn, yn := x.(float64), y.(float64)
+	switch n.Op {
+	case token.LSS:
+		return xn < yn, nil
+	case token.LEQ:
+		return xn <= yn, nil
+	case token.GTR:
+		return xn > yn, nil
+	case token.GEQ:
+		return xn >= yn, nil
+	case token.ADD:
+		return xn + yn, nil
+	case token.SUB:
+		return xn - yn, nil
+	case token.MUL:
+		return xn * yn, nil
+	case token.QUO:
+		if yn == 0 {
+			return nil, ev.errorf(n, "division by zero")
+		}
+		return xn / yn, nil
+	}
+	if yn == 0 {
+		return nil, ev.errorf(n, "division by zero")
+	}
+	return math.Mod(xn, yn), nil
+}
+
+func (ev *exprEval) evalCall(n *ast.CallExpr) (any, error) {
+	name := n.Fun.(*ast.Ident).Name
+	args := make([]any, len(n.Args))
+	for i, arg := range n.Args {
+		v, err := ev.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	if re, ok := ev.regexs[n]; ok {
+		return re.MatchString(args[0].(string)), nil
+	}
+
+	v, err := exprFuncs[name].call(args)
+	if err != nil {
+		return nil, ev.errorf(n, "%s: %s", name, err)
+	}
+	return v, nil
+}
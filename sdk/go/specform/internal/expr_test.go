@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileExpr_TypeErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"", "empty expression"},
+		{"len(output)", "expression must be a bool, got number"},
+		{"len(output) < ", "invalid expression"},
+		{"size(output) > 1", "column 1: unknown function size"},
+		{"contains(output, 3)", "column 1: cannot call contains with (string, number)"},
+		{"len(words(output)) < \"100\"", "mismatched types number < string"},
+		{"output.name == \"x\"", "cannot select .name from string"},
+		{"secret == \"x\"", "column 1: unknown identifier secret"},
+		{"matches(output, \"[\")", "column 17: invalid regex"},
+		{"!len(output)", "operator ! not defined on number"},
+		{"words(output) == words(reference)", "operator == not defined on list"},
+		{"os.Exit(1) == 0", "only built-in functions can be called"},
+		{"len(output) > 1 && func() bool { return true }()", "only built-in functions can be called"},
+		{strings.Repeat("x", maxExprLength+1), "longer than"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := CompileExpr(tt.expr)
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestExpr_Eval(t *testing.T) {
+	env := ExprEnv{
+		Output:    "Specform compiles specs. It renders prompts!\n\n- fast\n- safe",
+		Reference: "Specform compiles specs.",
+		Inputs:    map[string]string{"product": "specform", "limit": "12"},
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`len(words(output)) < 100 && contains(lower(output), inputs.product)`, true},
+		{`len(words(output)) == number(inputs["limit"]) - 4`, true},
+		{`len(sentences(output)) >= 2 && len(lines(output)) == 3`, true},
+		{`startsWith(output, reference) && !endsWith(output, "!")`, true},
+		{`contains(words(lower(output)), "renders") || 1 / 0 > 1`, true},
+		{`count(lower(output), "spec") == 2 && abs(-2) == 2 && 7 % 4 == 3`, true},
+		{`matches(output, "(?i)^specform") && matches(output, "(?m)^" + "- fast")`, true},
+		{`split(trim(output), "\n")[-1] == "- safe" && join(words("a b"), "+") == "a+b"`, true},
+		{`upper(inputs.product) == "SPECFORM" && "a" < "b" && (1 + 2) * 2 >= 6`, true},
+		{`len(output) > 1000`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := CompileExpr(tt.expr)
+			require.NoError(t, err)
+			got, err := e.Eval(context.Background(), env, ExprLimits{})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExpr_EvalErrors(t *testing.T) {
+	env := ExprEnv{Output: strings.Repeat("word ", 1000), Inputs: map[string]string{"n": "x"}}
+
+	tests := []struct {
+		expr   string
+		limits ExprLimits
+		err    string
+	}{
+		{`inputs.missing == ""`, ExprLimits{}, "input missing has no value"},
+		{`number(inputs.n) > 1`, ExprLimits{}, `number: "x" is not a number`},
+		{`words(output)[1000] == ""`, ExprLimits{}, "index 1000 out of range for list of length 1000"},
+		{`len(output) / 0 > 1`, ExprLimits{}, "division by zero"},
+		{`matches(output, inputs.n + "[")`, ExprLimits{}, "invalid regex"},
+		{`len(output + output) > 1`, ExprLimits{MaxSize: 6000}, "value larger than 6000 bytes"},
+		{`len(output) > 1 && len(output) > 2`, ExprLimits{MaxSteps: 4}, "exceeded 4 steps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := CompileExpr(tt.expr)
+			require.NoError(t, err)
+			_, err = e.Eval(context.Background(), env, tt.limits)
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+
+	e, err := CompileExpr(strings.Repeat("len(output) > 1 && ", 200) + "true")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = e.Eval(ctx, env, ExprLimits{})
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,195 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// FuncRegistry holds the functions that prompt templates may call, for
+// example {{upper tone}} or {{truncate article 2000}}.
+type FuncRegistry struct {
+	mu    sync.RWMutex
+	funcs template.FuncMap
+}
+
+// NewFuncRegistry creates an empty template function registry.
+func NewFuncRegistry() *FuncRegistry {
+	return &FuncRegistry{
+		funcs: template.FuncMap{},
+	}
+}
+
+// Register adds a template function. The function must return a single value,
+// or a value and an error.
+func (r *FuncRegistry) Register(name string, fn any) error {
+	if !identPattern.MatchString(name) {
+		return fmt.Errorf("Template function name %q is not a valid identifier", name)
+	}
+	if templateKeywords[name] || strings.HasPrefix(name, "__") {
+		return fmt.Errorf("Template function name %q is reserved", name)
+	}
+	if err := checkTemplateFunc(fn); err != nil {
+		return fmt.Errorf("Template function %s: %w", name, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.funcs[name]; exists {
+		return fmt.Errorf("Template function %s already registered", name)
+	}
+	r.funcs[name] = fn
+	return nil
+}
+
+// Unregister removes the function registered under name.
+func (r *FuncRegistry) Unregister(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.funcs[name]; !exists {
+		return fmt.Errorf("Template function %s not found", name)
+	}
+	delete(r.funcs, name)
+	return nil
+}
+
+// Has reports whether a function with the given name is registered.
+func (r *FuncRegistry) Has(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, exists := r.funcs[name]
+	return exists
+}
+
+// takesNoArguments reports whether name is a registered function that can be
+// called without arguments, such as {{today}}. It is false for a nil
+// registry.
+func (r *FuncRegistry) takesNoArguments(name string) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fn, exists := r.funcs[name]
+	if !exists {
+		return false
+	}
+	t := reflect.TypeOf(fn)
+	return t.NumIn() == 0 || (t.IsVariadic() && t.NumIn() == 1)
+}
+
+// FuncMap returns a copy of the registered functions.
+func (r *FuncRegistry) FuncMap() template.FuncMap {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make(template.FuncMap, len(r.funcs))
+	for k, v := range r.funcs {
+		out[k] = v
+	}
+	return out
+}
+
+// checkTemplateFunc mirrors the checks text/template performs, so a bad
+// function is rejected at registration instead of panicking on first use.
+func checkTemplateFunc(fn any) error {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return fmt.Errorf("value is not a function")
+	}
+	t := v.Type()
+	switch {
+	case t.NumOut() == 1:
+		return nil
+	case t.NumOut() == 2 && t.Out(1) == reflect.TypeOf((*error)(nil)).Elem():
+		return nil
+	}
+	return fmt.Errorf("function must return one value, or a value and an error")
+}
+
+// DefaultFuncs is the registry used when compiling and rendering prompts.
+var DefaultFuncs = initDefaultFuncs()
+
+func initDefaultFuncs() *FuncRegistry {
+	r := NewFuncRegistry()
+
+	r.Register("upper", strings.ToUpper)
+	r.Register("lower", strings.ToLower)
+	r.Register("trim", strings.TrimSpace)
+
+	// truncate shortens a value to at most n characters
+	r.Register("truncate", func(value string, n int) string {
+		runes := []rune(value)
+		if n < 0 || len(runes) <= n {
+			return value
+		}
+		return string(runes[:n])
+	})
+
+	// json encodes a value as JSON, which also quotes and escapes strings
+	r.Register("json", func(value any) (string, error) {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		return string(raw), nil
+	})
+
+	// join joins a list with a separator. String values are treated as a JSON
+	// array when they hold one, or as one item per line otherwise
+	r.Register("join", func(value any, sep string) string {
+		return strings.Join(toList(value), sep)
+	})
+
+	// default returns the fallback when the value is empty
+	r.Register("default", func(value, fallback string) string {
+		if strings.TrimSpace(value) == "" {
+			return fallback
+		}
+		return value
+	})
+
+	// indent prefixes every non-empty line with n spaces
+	r.Register("indent", func(value string, n int) string {
+		pad := strings.Repeat(" ", n)
+		lines := strings.Split(value, "\n")
+		for i, line := range lines {
+			if line != "" {
+				lines[i] = pad + line
+			}
+		}
+		return strings.Join(lines, "\n")
+	})
+
+	return r
+}
+
+func toList(value any) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []any:
+		out := make([]string, len(v))
+		for i, item := range v {
+			out[i] = fmt.Sprint(item)
+		}
+		return out
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if strings.HasPrefix(trimmed, "[") {
+			var items []any
+			if err := json.Unmarshal([]byte(trimmed), &items); err == nil {
+				return toList(items)
+			}
+		}
+		if trimmed == "" {
+			return nil
+		}
+		return strings.Split(trimmed, "\n")
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
@@ -3,7 +3,11 @@ package internal
 import (
 	"bufio"
 	"fmt"
+	"regexp"
+	"slices"
 	"strings"
+
+	"github.com/specform/specform/sdk/go/specform/types"
 )
 
 // ParseInputBlock parses the inputs block from a spec file and returns
@@ -89,3 +93,34 @@ func ParseInputBlock(content string) ([]string, map[string]string, error) {
 
 	return vars, defaults, nil
 }
+
+// ValidateInputOptions checks the per-input options declared in the spec
+// frontmatter.
+func ValidateInputOptions(options map[string]types.InputOptions) error {
+	for name, opt := range options {
+		switch opt.Truncate {
+		case "", types.TruncateHead, types.TruncateTail, types.TruncateMiddleOut, types.TruncateRefuse:
+		default:
+			return fmt.Errorf("invalid truncate strategy %q for input %s", opt.Truncate, name)
+		}
+		if opt.MaxTokens < 0 {
+			return fmt.Errorf("invalid max_tokens %d for input %s", opt.MaxTokens, name)
+		}
+		for _, policy := range opt.Escape {
+			switch policy {
+			case types.EscapeStripControl, types.EscapeFences, types.EscapeRejectInjection, types.EscapeDelimit:
+			default:
+				return fmt.Errorf("invalid escape policy %q for input %s", policy, name)
+			}
+		}
+		if len(opt.RejectPatterns) > 0 && !slices.Contains(opt.Escape, types.EscapeRejectInjection) {
+			return fmt.Errorf("reject_patterns for input %s need the %s escape policy", name, types.EscapeRejectInjection)
+		}
+		for _, pattern := range opt.RejectPatterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid reject pattern for input %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -76,6 +76,11 @@ func ParseSpecFile(path string) (*types.CompiledPrompt, error) {
 		return nil, fmt.Errorf("No prompt found in spec file")
 	}
 
+	// Make sure the prompt parses and only calls known template functions
+	if err := ValidateTemplate(scenario.Prompt, DefaultFuncs); err != nil {
+		return nil, err
+	}
+
 	// Parse the inputs
 	if val, ok := blocks["inputs"]; ok {
 		vars, defaults, err := ParseInputBlock(val)
@@ -87,12 +92,22 @@ func ParseSpecFile(path string) (*types.CompiledPrompt, error) {
 		scenario.Values = defaults
 	}
 
+	if err := ValidateInputOptions(scenario.InputOptions); err != nil {
+		return nil, fmt.Errorf("failed to parse input options: %w", err)
+	}
+	if scenario.MaxInputTokens < 0 {
+		return nil, fmt.Errorf("invalid max_input_tokens: %d", scenario.MaxInputTokens)
+	}
+
 	// Parse assertions
 	if val, ok := blocks["assertions"]; ok {
 		scenario.Assertions, err = ParseAssertionsBlock(val)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse assertions block: %w", err)
 		}
+		if err := ValidateAssertionValues(scenario.Assertions, DefaultFuncs); err != nil {
+			return nil, fmt.Errorf("failed to parse assertions block: %w", err)
+		}
 	}
 
 	// Optional Snapshot
@@ -2,7 +2,9 @@ package internal
 
 import (
 	"testing"
+	"time"
 
+	"github.com/specform/specform/sdk/go/specform/types"
 	"github.com/stretchr/testify/require"
 )
 
@@ -60,3 +62,120 @@ func TestParseSpec_InvalidFrontmatter(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "failed to parse frontmatter")
 }
+
+func TestParseAssertionsBlock_Quotes(t *testing.T) {
+	assertions, err := ParseAssertionsBlock(`
+- contains: "real time"
+- json-path-equals: $.sentiment "positive"
+- equals: "unterminated
+`)
+	require.NoError(t, err)
+	require.Len(t, assertions, 3)
+	require.Equal(t, "real time", assertions[0].Value)
+	require.Equal(t, `$.sentiment "positive"`, assertions[1].Value)
+	require.Equal(t, `"unterminated`, assertions[2].Value)
+}
+
+func TestParseAssertionsBlock_Options(t *testing.T) {
+	assertions, err := ParseAssertionsBlock(`
+- contains: "real time"
+  severity: warn
+  weight: 2.5
+- matches: /HTTP/i
+`)
+	require.NoError(t, err)
+	require.Len(t, assertions, 2)
+	require.Equal(t, "warn", assertions[0].Severity)
+	require.Equal(t, 2.5, assertions[0].Weight)
+	require.Empty(t, assertions[1].Severity)
+	require.Zero(t, assertions[1].Weight)
+
+	_, err = ParseAssertionsBlock("- contains: x\n  severity: fatal")
+	require.ErrorContains(t, err, `invalid severity "fatal" for assertion contains`)
+
+	_, err = ParseAssertionsBlock("- contains: x\n  weight: 0")
+	require.ErrorContains(t, err, `invalid weight "0"`)
+
+	assertions, err = ParseAssertionsBlock("- judge: Is it polite?\n  timeout: 30s")
+	require.NoError(t, err)
+	require.Equal(t, 30*time.Second, assertions[0].Timeout)
+
+	_, err = ParseAssertionsBlock("- judge: x\n  timeout: soon")
+	require.ErrorContains(t, err, `invalid timeout "soon"`)
+}
+
+func TestParseAssertionsBlock_Groups(t *testing.T) {
+	assertions, err := ParseAssertionsBlock(`
+- not-contains: error
+- any-of:
+  weight: 2
+  - contains: webhook
+  - all-of:
+    - contains: callback
+    - matches: /url/i
+- equals: done
+`)
+	require.NoError(t, err)
+	require.Len(t, assertions, 3)
+	require.Equal(t, "not-contains", assertions[0].Type)
+
+	group := assertions[1]
+	require.Equal(t, "any-of", group.Type)
+	require.Equal(t, 2.0, group.Weight)
+	require.Len(t, group.Assertions, 2)
+	require.Equal(t, "webhook", group.Assertions[0].Value)
+	require.Equal(t, "all-of", group.Assertions[1].Type)
+	require.Len(t, group.Assertions[1].Assertions, 2)
+	require.Equal(t, "/url/i", group.Assertions[1].Assertions[1].Value)
+
+	require.Equal(t, "equals", assertions[2].Type)
+	require.Empty(t, assertions[2].Assertions)
+
+	_, err = ParseAssertionsBlock("- any-of:\n- contains: x")
+	require.ErrorContains(t, err, "assertion group any-of has no assertions")
+}
+
+func TestValidateAssertionValues(t *testing.T) {
+	assertions, err := ParseAssertionsBlock(`
+- contains: "{{customer}}"
+- any-of:
+  - matches: /{{upper product}}/
+`)
+	require.NoError(t, err)
+	require.NoError(t, ValidateAssertionValues(assertions, DefaultFuncs))
+
+	assertions[1].Assertions[0].Value = "{{unknownFunc product}}"
+	require.ErrorContains(t, ValidateAssertionValues(assertions, DefaultFuncs), "invalid value for assertion matches")
+}
+
+func TestValidateAssertionValues_Expr(t *testing.T) {
+	assertions, err := ParseAssertionsBlock(`
+- expr: len(words(output)) < 100
+- not-expr: contains(output, 3)
+`)
+	require.NoError(t, err)
+	require.NoError(t, ValidateAssertionValues(assertions[:1], DefaultFuncs))
+	require.ErrorContains(t, ValidateAssertionValues(assertions, DefaultFuncs), "invalid expression for assertion not-expr: column 1: cannot call contains with (string, number)")
+}
+
+func TestValidateAssertionValues_Code(t *testing.T) {
+	assertions, err := ParseAssertionsBlock(`
+- valid-code: golang
+- code-block-count: go >= 1
+- valid-code: rust
+`)
+	require.NoError(t, err)
+	require.NoError(t, ValidateAssertionValues(assertions[:2], DefaultFuncs))
+	require.ErrorContains(t, ValidateAssertionValues(assertions, DefaultFuncs), `invalid value for assertion valid-code: unsupported language "rust"`)
+}
+
+func TestValidateInputOptions_RejectPatterns(t *testing.T) {
+	err := ValidateInputOptions(map[string]types.InputOptions{
+		"article": {RejectPatterns: []string{`acme`}},
+	})
+	require.ErrorContains(t, err, "reject_patterns for input article need the reject-injection escape policy")
+
+	require.NoError(t, ValidateInputOptions(map[string]types.InputOptions{
+		"article": {Escape: []string{types.EscapeRejectInjection}, RejectPatterns: []string{`acme`}},
+	}))
+}
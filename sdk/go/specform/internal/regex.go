@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// regexFlags maps the flags accepted after a /pattern/ literal to Go
+// regexp flags. g is accepted and ignored, since every match is searched.
+var regexFlags = map[rune]string{
+	'i': "i", // case insensitive
+	'm': "m", // ^ and $ match at line breaks
+	's': "s", // . matches \n
+	'U': "U", // ungreedy
+	'g': "",
+}
+
+// SplitRegexLiteral splits an assertion value into a regex literal and the
+// rest of the value, e.g. `/\d+/i >= 2` into `/\d+/i` and `>= 2`. A value
+// that does not start with a slash is split at the first whitespace.
+func SplitRegexLiteral(value string) (literal, rest string) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "/") {
+		// Try the closing slashes from the last one, so patterns may
+		// contain slashes
+		for i := len(value) - 1; i > 0; i-- {
+			if value[i] != '/' {
+				continue
+			}
+			after := value[i+1:]
+			end := strings.IndexFunc(after, unicode.IsSpace)
+			if end < 0 {
+				end = len(after)
+			}
+			if validRegexFlags(after[:end]) {
+				return value[:i+1+end], strings.TrimSpace(after[end:])
+			}
+		}
+	}
+
+	literal, rest, _ = strings.Cut(value, " ")
+	return literal, strings.TrimSpace(rest)
+}
+
+func validRegexFlags(flags string) bool {
+	for _, f := range flags {
+		if _, ok := regexFlags[f]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// CompileRegex compiles a `/pattern/flags` literal, or a bare pattern
+// without flags. The flags i, m, s and U set the Go regexp flags of the same
+// name.
+func CompileRegex(literal string) (*regexp.Regexp, error) {
+	pattern, flags := literal, ""
+	if strings.HasPrefix(literal, "/") {
+		if i := strings.LastIndex(literal, "/"); i > 0 {
+			pattern, flags = literal[1:i], literal[i+1:]
+		}
+	}
+
+	var goFlags strings.Builder
+	for _, f := range flags {
+		flag, ok := regexFlags[f]
+		if !ok {
+			return nil, fmt.Errorf("unknown flag %q", f)
+		}
+		if !strings.Contains(goFlags.String(), flag) {
+			goFlags.WriteString(flag)
+		}
+	}
+	if goFlags.Len() > 0 {
+		pattern = "(?" + goFlags.String() + ")" + pattern
+	}
+
+	return regexp.Compile(pattern)
+}
+
+// ValidateRegexAssertion compiles the regex of a matches, match-count or
+// capture-equals assertion value and checks the rest of the value.
+func ValidateRegexAssertion(typ, value string) error {
+	literal, rest := value, ""
+	if typ != "matches" {
+		literal, rest = SplitRegexLiteral(value)
+	}
+
+	re, err := CompileRegex(literal)
+	if err != nil {
+		return fmt.Errorf("invalid regex %s: %w", literal, err)
+	}
+
+	switch typ {
+	case "match-count":
+		if rest == "" {
+			return fmt.Errorf("missing count after %s, e.g. \">= 2\"", literal)
+		}
+	case "capture-equals":
+		name, _, _ := strings.Cut(rest, " ")
+		if name == "" {
+			return fmt.Errorf("missing capture group name after %s", literal)
+		}
+		if re.SubexpIndex(name) < 0 {
+			return fmt.Errorf("regex %s has no capture group named %s", literal, name)
+		}
+	}
+	return nil
+}
+
+// RegexQuoteFunc is the name of the function that regex value templates pipe
+// the actions inside the regex through.
+const RegexQuoteFunc = "__regexquote"
+
+// IsRegexAssertion reports whether the value of an assertion type starts
+// with a regex literal.
+func IsRegexAssertion(typ string) bool {
+	switch typ {
+	case "matches", "match-count", "capture-equals":
+		return true
+	}
+	return false
+}
+
+// ParseRegexTemplate parses the value of a matches, match-count or
+// capture-equals assertion like ParseTemplate, but quotes the value of every
+// action inside the regex, so inputs such as "C++" are matched literally.
+// Actions after the regex, such as the expected capture, are left as is.
+func ParseRegexTemplate(name, typ, value string, registry *FuncRegistry) (*template.Template, error) {
+	end := regexLiteralEnd(typ, value)
+	normalized, _ := normalizeTemplate(value, registry, func(_, offset int) string {
+		if offset < end {
+			return RegexQuoteFunc
+		}
+		return ""
+	})
+
+	funcs := registry.FuncMap()
+	funcs[RegexQuoteFunc] = func(value any) string { return regexp.QuoteMeta(fmt.Sprint(value)) }
+
+	return template.New(name).Option("missingkey=error").Funcs(funcs).Parse(normalized)
+}
+
+// regexLiteralEnd returns the byte offset in a templated value where its
+// regex literal ends. Actions are masked while splitting, so slashes and
+// spaces inside them do not move the split.
+func regexLiteralEnd(typ, value string) int {
+	if typ == "matches" {
+		return len(value)
+	}
+
+	locs := actionPattern.FindAllStringIndex(value, -1)
+	var masked strings.Builder
+	last := 0
+	for _, loc := range locs {
+		masked.WriteString(value[last:loc[0]])
+		masked.WriteString("x")
+		last = loc[1]
+	}
+	masked.WriteString(value[last:])
+
+	m := masked.String()
+	literal, _ := SplitRegexLiteral(m)
+	end := len(m) - len(strings.TrimLeftFunc(m, unicode.IsSpace)) + len(literal)
+
+	// Map the end back to value, adding the length of every masked action
+	// that starts before it
+	shift := 0
+	for _, loc := range locs {
+		if loc[0]-shift >= end {
+			break
+		}
+		shift += loc[1] - loc[0] - 1
+	}
+	return end + shift
+}
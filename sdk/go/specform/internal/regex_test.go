@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitRegexLiteral(t *testing.T) {
+	tests := []struct {
+		value, literal, rest string
+	}{
+		{`/\d+/i >= 2`, `/\d+/i`, ">= 2"},
+		{`/a/b/ 3`, `/a/b/`, "3"},
+		{`/Order #(?P<id>\d+)/ id 4/2`, `/Order #(?P<id>\d+)/`, "id 4/2"},
+		{`/a b/ms`, `/a b/ms`, ""},
+		{`\d+ >= 1`, `\d+`, ">= 1"},
+	}
+	for _, tt := range tests {
+		literal, rest := SplitRegexLiteral(tt.value)
+		require.Equal(t, tt.literal, literal, tt.value)
+		require.Equal(t, tt.rest, rest, tt.value)
+	}
+}
+
+func TestCompileRegex_Flags(t *testing.T) {
+	re, err := CompileRegex(`/^second.line$/ims`)
+	require.NoError(t, err)
+	require.True(t, re.MatchString("first\nSECOND\nLINE"))
+
+	re, err = CompileRegex(`/<.+>/U`)
+	require.NoError(t, err)
+	require.Equal(t, "<a>", re.FindString("<a><b>"))
+
+	re, err = CompileRegex(`/webhook/g`)
+	require.NoError(t, err)
+	require.True(t, re.MatchString("webhook"))
+
+	_, err = CompileRegex(`/webhook/x`)
+	require.ErrorContains(t, err, `unknown flag 'x'`)
+}
+
+func TestValidateRegexAssertion(t *testing.T) {
+	require.NoError(t, ValidateRegexAssertion("matches", `/retr(y|ies)/i`))
+	require.NoError(t, ValidateRegexAssertion("match-count", `/webhook/ >= 2`))
+	require.NoError(t, ValidateRegexAssertion("capture-equals", `/Order #(?P<id>\d+)/ id 42`))
+
+	require.ErrorContains(t, ValidateRegexAssertion("matches", `/[unterminated/`), "invalid regex /[unterminated/")
+	require.ErrorContains(t, ValidateRegexAssertion("match-count", `/webhook/`), "missing count")
+	require.ErrorContains(t, ValidateRegexAssertion("capture-equals", `/Order #(?P<id>\d+)/ total 42`), "has no capture group named total")
+}
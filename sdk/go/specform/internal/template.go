@@ -0,0 +1,268 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// actionPattern matches a single {{ ... }} action in a prompt template.
+var actionPattern = regexp.MustCompile(`(?s){{(.*?)}}`)
+
+// identPattern matches a bare identifier such as an input or function name.
+var identPattern = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+
+// templateKeywords are the text/template control words that must be left
+// untouched when normalizing an action.
+var templateKeywords = map[string]bool{
+	"if": true, "else": true, "end": true, "range": true, "with": true,
+	"define": true, "template": true, "block": true, "break": true, "continue": true,
+	"true": true, "false": true, "nil": true,
+}
+
+// ParseTemplate normalizes a spec prompt and parses it as a Go template using
+// the functions in registry. Unknown functions are reported as errors.
+func ParseTemplate(name, prompt string, registry *FuncRegistry) (*template.Template, error) {
+	tpl, err := template.New(name).
+		Option("missingkey=error").
+		Funcs(registry.FuncMap()).
+		Parse(NormalizeTemplate(prompt, registry))
+	if err != nil {
+		return nil, err
+	}
+	return tpl, nil
+}
+
+// TraceFunc is the name of the function that trace templates pipe every
+// output action through. It is bound per execution to record output spans.
+const TraceFunc = "__trace"
+
+// templateAction describes an action of a normalized template.
+type templateAction struct {
+	refs   []string // inputs referenced by the action
+	output bool     // true if the action writes a value to the output
+}
+
+// NormalizeTemplate rewrites the mustache style references used in spec files
+// into Go template syntax. Bare identifiers that are used as values become
+// field lookups, so {{article}} becomes {{.article}} and
+// {{truncate article 2000}} becomes {{truncate .article 2000}}. Functions
+// in registry that take no arguments are called instead, so {{today}} stays
+// a call; registry may be nil.
+func NormalizeTemplate(prompt string, registry *FuncRegistry) string {
+	normalized, _ := normalizeTemplate(prompt, registry, nil)
+	return normalized
+}
+
+// TemplateRefs returns the inputs referenced by a prompt template, in order of
+// first use. Calls of the functions in registry are not references.
+func TemplateRefs(prompt string, registry *FuncRegistry) []string {
+	_, actions := normalizeTemplate(prompt, registry, nil)
+	seen := map[string]bool{}
+	var refs []string
+	for _, a := range actions {
+		for _, ref := range a.refs {
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// ParseTraceTemplate parses a prompt like ParseTemplate, but pipes the value
+// of every output action through TraceFunc with the action's index. The
+// returned slice holds the inputs referenced by each indexed action.
+func ParseTraceTemplate(name, prompt string, registry *FuncRegistry) (*template.Template, [][]string, error) {
+	normalized, actions := normalizeTemplate(prompt, registry, func(index, _ int) string {
+		return fmt.Sprintf("%s %d", TraceFunc, index)
+	})
+
+	funcs := registry.FuncMap()
+	funcs[TraceFunc] = func(_ int, value any) string { return fmt.Sprint(value) }
+
+	tpl, err := template.New(name).Option("missingkey=error").Funcs(funcs).Parse(normalized)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refs := make([][]string, len(actions))
+	for i, a := range actions {
+		refs[i] = a.refs
+	}
+	return tpl, refs, nil
+}
+
+// normalizeTemplate normalizes every action of a prompt. When pipe is set,
+// it is called with the index and byte offset of every output action and the
+// value of the action is piped through the command it returns, if any.
+func normalizeTemplate(prompt string, registry *FuncRegistry, pipe func(index, offset int) string) (string, []templateAction) {
+	var actions []templateAction
+	var sb strings.Builder
+	last := 0
+	for _, loc := range actionPattern.FindAllStringIndex(prompt, -1) {
+		sb.WriteString(prompt[last:loc[0]])
+		last = loc[1]
+
+		action := prompt[loc[0]:loc[1]]
+		inner := action[2 : len(action)-2]
+		if strings.HasPrefix(strings.TrimLeft(inner, "- "), "/*") {
+			sb.WriteString(action)
+			continue
+		}
+
+		tokens, a := normalizeAction(inner, registry)
+		if pipe != nil && a.output {
+			if command := pipe(len(actions), loc[0]); command != "" {
+				tokens = appendPipe(tokens, command)
+			}
+		}
+		actions = append(actions, a)
+		sb.WriteString("{{" + strings.Join(tokens, "") + "}}")
+	}
+	sb.WriteString(prompt[last:])
+	return sb.String(), actions
+}
+
+// normalizeAction rewrites the identifiers of a single action body. An
+// identifier is treated as a function call when it starts a command and is
+// followed by arguments, receives a piped value or names a function in
+// registry that takes no arguments; every other bare identifier is an input
+// reference.
+func normalizeAction(inner string, registry *FuncRegistry) ([]string, templateAction) {
+	tokens := tokenizeAction(inner)
+	action := templateAction{output: true}
+
+	commandStart := true
+	piped := false
+	first := true
+	for i, tok := range tokens {
+		if isSpace(tok) || tok == "-" {
+			continue
+		}
+		if first && templateKeywords[tok] {
+			action.output = false
+		}
+		first = false
+
+		switch {
+		case tok == "|":
+			commandStart, piped = true, true
+			continue
+		case tok == "(":
+			commandStart, piped = true, false
+			continue
+		case tok == ":=" || tok == "=":
+			action.output = false
+			commandStart, piped = true, false
+			continue
+		case tok == ")":
+			continue
+		case templateKeywords[tok]:
+			commandStart, piped = true, false
+			continue
+		}
+
+		switch {
+		case identPattern.MatchString(tok):
+			isCall := commandStart && (piped || hasArguments(tokens[i+1:]) || registry.takesNoArguments(tok))
+			if !isCall {
+				tokens[i] = "." + tok
+				action.refs = append(action.refs, tok)
+			}
+		case strings.HasPrefix(tok, ".") && identPattern.MatchString(tok[1:]):
+			action.refs = append(action.refs, tok[1:])
+		}
+		commandStart, piped = false, false
+	}
+
+	return tokens, action
+}
+
+// appendPipe pipes the action's value through command, keeping any
+// trailing trim marker at the end of the action.
+func appendPipe(tokens []string, command string) []string {
+	end := len(tokens)
+	for end > 0 && isSpace(tokens[end-1]) {
+		end--
+	}
+	if end > 0 && tokens[end-1] == "-" {
+		end--
+		for end > 0 && isSpace(tokens[end-1]) {
+			end--
+		}
+	}
+
+	out := make([]string, 0, len(tokens)+1)
+	out = append(out, tokens[:end]...)
+	out = append(out, " | "+command)
+	return append(out, tokens[end:]...)
+}
+
+// hasArguments reports whether the command continues after the current token.
+func hasArguments(rest []string) bool {
+	for _, tok := range rest {
+		if isSpace(tok) {
+			continue
+		}
+		return tok != "|" && tok != ")" && tok != "-"
+	}
+	return false
+}
+
+// tokenizeAction splits an action body into words, quoted strings, pipes,
+// parentheses and whitespace runs. Joining the tokens yields the input.
+func tokenizeAction(s string) []string {
+	var tokens []string
+	runes := []rune(s)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+
+		switch {
+		case unicode.IsSpace(r):
+			for i < len(runes) && unicode.IsSpace(runes[i]) {
+				i++
+			}
+		case r == '"' || r == '`' || r == '\'':
+			i++
+			for i < len(runes) && runes[i] != r {
+				if runes[i] == '\\' && r != '`' {
+					i++
+				}
+				i++
+			}
+			i++
+		case r == '|' || r == '(' || r == ')':
+			i++
+		default:
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("|()\"`'", runes[i]) {
+				i++
+			}
+		}
+
+		if i > len(runes) {
+			i = len(runes)
+		}
+		tokens = append(tokens, string(runes[start:i]))
+	}
+
+	return tokens
+}
+
+func isSpace(tok string) bool {
+	return strings.TrimSpace(tok) == ""
+}
+
+// ValidateTemplate checks that a prompt template parses and only calls
+// registered functions.
+func ValidateTemplate(prompt string, registry *FuncRegistry) error {
+	if _, err := ParseTemplate("prompt", prompt, registry); err != nil {
+		return fmt.Errorf("invalid prompt template: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTemplate(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"Hello {{name}}", "Hello {{.name}}"},
+		{"Hello {{ name }}", "Hello {{ .name }}"},
+		{"{{truncate article 2000}}", "{{truncate .article 2000}}"},
+		{"{{article | upper}}", "{{.article | upper}}"},
+		{"{{upper (truncate article 10)}}", "{{upper (truncate .article 10)}}"},
+		{`{{join tags ", "}}`, `{{join .tags ", "}}`},
+		{"{{- trim article -}}", "{{- trim .article -}}"},
+		{"{{if tone}}{{tone}}{{else}}plain{{end}}", "{{if .tone}}{{.tone}}{{else}}plain{{end}}"},
+		{"{{ .article }}", "{{ .article }}"},
+		{"{{/* a comment */}}", "{{/* a comment */}}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			require.Equal(t, tt.expected, NormalizeTemplate(tt.in, nil))
+		})
+	}
+}
+
+func TestNormalizeTemplate_ZeroArgumentFuncs(t *testing.T) {
+	registry := NewFuncRegistry()
+	require.NoError(t, registry.Register("today", func() string { return "" }))
+	require.NoError(t, registry.Register("upper", strings.ToUpper))
+
+	require.Equal(t, "{{today}} {{if today}}{{.upper}}{{end}}", NormalizeTemplate("{{today}} {{if today}}{{upper}}{{end}}", registry))
+	require.Equal(t, []string{"upper"}, TemplateRefs("{{today}} {{upper}}", registry))
+}
+
+func TestParseTemplate_BuiltinFuncs(t *testing.T) {
+	prompt := `{{upper tone}}|{{truncate article 5}}|{{json article}}|{{join tags ", "}}|{{default missing "none"}}|{{indent code 2}}`
+	tpl, err := ParseTemplate("prompt", prompt, DefaultFuncs)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = tpl.Execute(&buf, map[string]string{
+		"tone":    "casual",
+		"article": "Webhooks \"rock\"",
+		"tags":    `["a", "b"]`,
+		"missing": "",
+		"code":    "x\ny",
+	})
+	require.NoError(t, err)
+	require.Equal(t, `CASUAL|Webho|"Webhooks \"rock\""|a, b|none|  x
+  y`, buf.String())
+}
+
+func TestValidateTemplate_UnknownFunc(t *testing.T) {
+	err := ValidateTemplate("{{shout article}}", DefaultFuncs)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `function "shout" not defined`)
+}
+
+func TestFuncRegistry_Register(t *testing.T) {
+	r := NewFuncRegistry()
+	require.NoError(t, r.Register("shout", func(s string) string { return s + "!" }))
+	require.True(t, r.Has("shout"))
+	require.Error(t, r.Register("shout", func(s string) string { return s }))
+	require.Error(t, r.Register("bad", "not a function"))
+	require.Error(t, r.Register("noresult", func(s string) {}))
+	require.Error(t, r.Register("if", func(s string) string { return s }))
+}
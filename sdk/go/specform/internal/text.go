@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Text measures shared by the metric assertions and the expr functions, so
+// `word-count` and `len(words(output))` always agree.
+
+var (
+	wordPattern           = regexp.MustCompile(`[\pL\pN]+(?:['’-][\pL\pN]+)*`)
+	sentenceEndPattern    = regexp.MustCompile(`[.!?…]+["'”’)\]]*(?:\s+|$)`)
+	letterOrNumberPattern = regexp.MustCompile(`[\pL\pN]`)
+)
+
+// Words returns the runs of letters and numbers in s. Contractions and
+// hyphenated words count once.
+func Words(s string) []string {
+	return wordPattern.FindAllString(s, -1)
+}
+
+// Sentences returns the text segments of s ending in ., !, ? or … followed
+// by whitespace or the end of s, trimmed. Decimals such as 3.5 do not end a
+// sentence.
+func Sentences(s string) []string {
+	var sentences []string
+	for _, part := range sentenceEndPattern.Split(s, -1) {
+		if letterOrNumberPattern.MatchString(part) {
+			sentences = append(sentences, strings.TrimSpace(part))
+		}
+	}
+	return sentences
+}
+
+// Lines returns the lines of s that are not blank.
+func Lines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -1,18 +1,31 @@
 package specform
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/specform/specform/sdk/go/specform/types"
 )
 
 type AssertionFn func(value string, output string, ctx *types.AssertionContext) types.AssertionResult
 
+// ContextAssertionFn is an assertion that takes a context. The context is
+// cancelled when the assertion times out or the run is cancelled, so slow
+// assertions such as network calls should pass it on.
+type ContextAssertionFn func(ctx context.Context, value, output string, actx *types.AssertionContext) types.AssertionResult
+
+// AssertionRegistry maps assertion types to their implementations. It is
+// safe for concurrent use, so assertions can be registered while others run.
 type AssertionRegistry struct {
-	registry map[string]AssertionFn
+	mu       sync.RWMutex
+	registry map[string]ContextAssertionFn
 }
 
 /**
@@ -20,11 +33,19 @@ type AssertionRegistry struct {
  */
 func NewAssertionRegistry() *AssertionRegistry {
 	return &AssertionRegistry{
-		registry: make(map[string]AssertionFn),
+		registry: make(map[string]ContextAssertionFn),
 	}
 }
 
 func (r *AssertionRegistry) Register(name string, fn AssertionFn) error {
+	return r.RegisterContext(name, withoutContext(fn))
+}
+
+// RegisterContext registers an assertion that takes a context.
+func (r *AssertionRegistry) RegisterContext(name string, fn ContextAssertionFn) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if _, exists := r.registry[name]; exists {
 		return fmt.Errorf("Assertion %s already registered", name)
 	}
@@ -32,7 +53,81 @@ func (r *AssertionRegistry) Register(name string, fn AssertionFn) error {
 	return nil
 }
 
+// Override registers fn under name, replacing any existing assertion,
+// including built-ins.
+func (r *AssertionRegistry) Override(name string, fn AssertionFn) {
+	r.OverrideContext(name, withoutContext(fn))
+}
+
+// OverrideContext is like Override for an assertion that takes a context.
+func (r *AssertionRegistry) OverrideContext(name string, fn ContextAssertionFn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.registry[name] = fn
+}
+
+// withoutContext adapts an AssertionFn to a ContextAssertionFn.
+func withoutContext(fn AssertionFn) ContextAssertionFn {
+	return func(_ context.Context, value, output string, actx *types.AssertionContext) types.AssertionResult {
+		return fn(value, output, actx)
+	}
+}
+
+// Unregister removes the assertion registered under name.
+func (r *AssertionRegistry) Unregister(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.registry[name]; !exists {
+		return fmt.Errorf("Assertion %s not found", name)
+	}
+	delete(r.registry, name)
+	return nil
+}
+
+// Clone returns an independent copy of the registry. Changes to the clone do
+// not affect the original, which makes it suitable for per-tenant registries.
+func (r *AssertionRegistry) Clone() *AssertionRegistry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	clone := NewAssertionRegistry()
+	for name, fn := range r.registry {
+		clone.registry[name] = fn
+	}
+	return clone
+}
+
+// Names returns the registered assertion types in sorted order.
+func (r *AssertionRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.registry))
+	for name := range r.registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (r *AssertionRegistry) Get(name string) (AssertionFn, error) {
+	fn, err := r.GetContext(name)
+	if err != nil {
+		return nil, err
+	}
+	return func(value, output string, actx *types.AssertionContext) types.AssertionResult {
+		return fn(context.Background(), value, output, actx)
+	}, nil
+}
+
+// GetContext returns the assertion registered under name as a
+// ContextAssertionFn.
+func (r *AssertionRegistry) GetContext(name string) (ContextAssertionFn, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	if fn, exists := r.registry[name]; exists {
 		return fn, nil
 	}
@@ -40,43 +135,200 @@ func (r *AssertionRegistry) Get(name string) (AssertionFn, error) {
 }
 
 func (r *AssertionRegistry) Has(name string) bool {
-	if _, exists := r.registry[name]; exists {
-		return true
-	}
-	return false
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, exists := r.registry[name]
+	return exists
 }
 
+// Run runs the assertion registered under name. A name with the not- prefix
+// that is not registered itself runs the assertion without the prefix and
+// inverts its result.
 func (r *AssertionRegistry) Run(name, value, output string, ctx *types.AssertionContext) (types.AssertionResult, error) {
-	fn, err := r.Get(name)
+	return r.runAssertion(context.Background(), types.Assertion{Type: name, Value: value}, output, ctx)
+}
+
+func (r *AssertionRegistry) RunAll(output string, assertions []types.Assertion, ctx *types.AssertionContext) []types.AssertionResult {
+	return r.RunAllContext(context.Background(), output, assertions, ctx)
+}
+
+// RunAllContext runs the assertions until ctx is cancelled. Assertions that
+// panic, time out or are cancelled fail with an error. When
+// AssertionContext.Concurrency is above 1, up to that many assertions are
+// evaluated at once; results are always in the order of the assertions.
+func (r *AssertionRegistry) RunAllContext(ctx context.Context, output string, assertions []types.Assertion, actx *types.AssertionContext) []types.AssertionResult {
+	results := make([]types.AssertionResult, len(assertions))
+	run := func(i int) {
+		// Unknown assertion types are reported in the result
+		a := assertions[i]
+		results[i], _ = r.runAssertion(ctx, a, output, actx)
+		results[i].Weight, results[i].Severity = a.Weight, a.Severity
+	}
+
+	concurrency := 1
+	if actx != nil {
+		concurrency = actx.Concurrency
+	}
+	if concurrency <= 1 || len(assertions) <= 1 {
+		for i := range assertions {
+			run(i)
+		}
+		return results
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i := range assertions {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			run(i)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// runAssertion runs a single assertion, an any-of or all-of group, or a
+// negated assertion. It returns an error when the type is not registered.
+func (r *AssertionRegistry) runAssertion(ctx context.Context, a types.Assertion, output string, actx *types.AssertionContext) (types.AssertionResult, error) {
+	// Values are interpolated once, so input values are never rendered
+	value, err := interpolateValue(a.Type, a.Value, actx)
 	if err != nil {
-		return types.AssertionResult{
-			Type:    name,
-			Value:   value,
-			Passed:  false,
-			Message: "✘ " + err.Error(),
-		}, err
+		return errorResult(a.Type, a.Value, err.Error()), nil
+	}
+	a.Value = value
+
+	// The assertion's own timeout also bounds the members of a group
+	if a.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.Timeout)
+		defer cancel()
 	}
-	return fn(value, output, ctx), nil
+
+	start := time.Now()
+	result, err := r.evaluate(ctx, a, output, actx)
+	result.Duration = time.Since(start)
+	return result, err
 }
 
-func (r *AssertionRegistry) RunAll(output string, assertions []types.Assertion, ctx *types.AssertionContext) []types.AssertionResult {
-	results := make([]types.AssertionResult, 0, len(assertions))
+// evaluate runs an assertion whose value has been interpolated.
+func (r *AssertionRegistry) evaluate(ctx context.Context, a types.Assertion, output string, actx *types.AssertionContext) (types.AssertionResult, error) {
+	switch {
+	case a.Type == types.AssertionAnyOf || a.Type == types.AssertionAllOf:
+		return r.runGroup(ctx, a, output, actx), nil
 
-	for _, a := range assertions {
-		result, err := r.Run(a.Type, a.Value, output, ctx)
+	case strings.HasPrefix(a.Type, types.NegatePrefix) && !r.Has(a.Type):
+		inner := a
+		inner.Type = strings.TrimPrefix(a.Type, types.NegatePrefix)
+		result, err := r.evaluate(ctx, inner, output, actx)
 		if err != nil {
-			results = append(results, types.AssertionResult{
-				Type:    a.Type,
-				Value:   a.Value,
-				Passed:  false,
-				Message: "✘ " + err.Error(),
-			})
-			continue
+			result.Type = a.Type
+			return result, err
 		}
-		results = append(results, result)
+		return negateResult(a.Type, result), nil
 	}
 
-	return results
+	fn, err := r.GetContext(a.Type)
+	if err != nil {
+		return errorResult(a.Type, a.Value, err.Error()), err
+	}
+	return callAssertion(ctx, fn, a, output, actx), nil
+}
+
+// callAssertion calls fn, turning a panic into a failed result. With a
+// timeout or a cancellable context, fn runs in its own goroutine and the
+// assertion fails once the context is done. An assertion that ignores its
+// context keeps running in the background until it returns.
+func callAssertion(ctx context.Context, fn ContextAssertionFn, a types.Assertion, output string, actx *types.AssertionContext) types.AssertionResult {
+	if err := ctx.Err(); err != nil {
+		return contextErrorResult(a, err)
+	}
+
+	if a.Timeout == 0 && actx != nil && actx.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, actx.Timeout)
+		defer cancel()
+	}
+
+	if ctx.Done() == nil {
+		return safeCall(ctx, fn, a, output, actx)
+	}
+
+	done := make(chan types.AssertionResult, 1)
+	go func() {
+		done <- safeCall(ctx, fn, a, output, actx)
+	}()
+
+	select {
+	case result := <-done:
+		return result
+	case <-ctx.Done():
+		return contextErrorResult(a, ctx.Err())
+	}
+}
+
+// safeCall calls fn and recovers from a panic.
+func safeCall(ctx context.Context, fn ContextAssertionFn, a types.Assertion, output string, actx *types.AssertionContext) (result types.AssertionResult) {
+	defer func() {
+		if p := recover(); p != nil {
+			result = errorResult(a.Type, a.Value, fmt.Sprintf("Assertion panicked: %v", p))
+		}
+	}()
+	return fn(ctx, a.Value, output, actx)
+}
+
+// contextErrorResult is the result of an assertion stopped by its context.
+func contextErrorResult(a types.Assertion, err error) types.AssertionResult {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errorResult(a.Type, a.Value, "Assertion timed out")
+	}
+	return errorResult(a.Type, a.Value, fmt.Sprintf("Assertion cancelled: %s", err))
+}
+
+// runGroup runs the assertions of an any-of or all-of group. The group
+// result holds the result of every member.
+func (r *AssertionRegistry) runGroup(ctx context.Context, a types.Assertion, output string, actx *types.AssertionContext) types.AssertionResult {
+	results := r.RunAllContext(ctx, output, a.Assertions, actx)
+
+	count := 0
+	for _, res := range results {
+		if res.Passed {
+			count++
+		}
+	}
+
+	passed := count == len(results)
+	if a.Type == types.AssertionAnyOf {
+		passed = count > 0
+	}
+
+	msg := fmt.Sprintf("%s %s: %d of %d passed", boolPrefix(passed), a.Type, count, len(results))
+	return types.AssertionResult{Type: a.Type, Value: a.Value, Passed: passed, Message: msg, Results: results}
+}
+
+// negateResult inverts a result for a not- assertion. Results that could
+// not be evaluated stay failed.
+func negateResult(name string, result types.AssertionResult) types.AssertionResult {
+	result.Type = name
+	if result.Error != "" {
+		return result
+	}
+
+	result.Passed = !result.Passed
+	msg := strings.TrimPrefix(strings.TrimPrefix(result.Message, "✔ "), "✘ ")
+	result.Message = boolPrefix(result.Passed) + " Not: " + msg
+	return result
+}
+
+// errorResult is the result of an assertion that could not be evaluated,
+// for example because its value is invalid. Negation keeps it failed.
+func errorResult(typ, value, msg string) types.AssertionResult {
+	return types.AssertionResult{Type: typ, Value: value, Passed: false, Message: "✘ " + msg, Error: msg}
 }
 
 var defaultRegistry = initDefaultRegistry()
@@ -93,7 +345,7 @@ func initDefaultRegistry() *AssertionRegistry {
 		// Check if the normalized output contains the normalized value
 		passed := strings.Contains(normalizedOutput, normalizedValue)
 		msg := passFailMsg(passed, "Output contains '%s'", "Output missing '%s'", value)
-		return types.AssertionResult{Type: "contains", Value: value, Passed: passed, Message: msg}
+		return types.AssertionResult{Type: "contains", Value: value, Passed: passed, Message: msg, Expected: value, Spans: normalizedSpans(output, normalizedValue)}
 	})
 
 	// equals
@@ -103,39 +355,29 @@ func initDefaultRegistry() *AssertionRegistry {
 		normalizedValue := normalizeText(value)
 		// Check if the normalized output equals the normalized value
 		passed := strings.TrimSpace(normalizedOutput) == strings.TrimSpace(normalizedValue)
-		msg := passFailMsg(passed, "Output exactly matches expected value", "Output does not match expected value", "")
-		return types.AssertionResult{Type: "equals", Value: value, Passed: passed, Message: msg}
-	})
-
-	// matches
-	r.Register("matches", func(value, output string, _ *types.AssertionContext) types.AssertionResult {
-
-		pattern, flags := parseRegex(value)
-
-		var re *regexp.Regexp
-		var err error
-		if strings.Contains(flags, "i") {
-			re, err = regexp.Compile("(?i)" + pattern)
-		} else {
-			re, err = regexp.Compile(pattern)
+		msg := "✔ Output exactly matches expected value"
+		if !passed {
+			msg = "✘ Output does not match expected value"
 		}
-
-		if err != nil {
-			return types.AssertionResult{Type: "matches", Value: value, Passed: false, Message: fmt.Sprintf("✘ Invalid regex: %s", err)}
+		result := types.AssertionResult{Type: "equals", Value: value, Passed: passed, Message: msg, Expected: value}
+		if !passed {
+			// The output is only copied when it explains a failure
+			result.Actual = strings.TrimSpace(output)
+			result.Diff = unifiedDiff(strings.TrimSpace(value), result.Actual)
 		}
-
-		passed := re.MatchString(output)
-		msg := passFailMsg(passed, "Output matches regex %s", "Output does not match regex %s", value)
-		return types.AssertionResult{Type: "matches", Value: value, Passed: passed, Message: msg}
+		return result
 	})
 
+	// Regex assertions, see regex.go
+	r.Register("matches", assertMatches)
+	r.Register("match-count", assertMatchCount)
+	r.Register("capture-equals", assertCaptureEquals)
+
 	// semantic-similarity
-	r.Register("semantic-similarity", func(value, _ string, ctx *types.AssertionContext) types.AssertionResult {
-		score := 0.0
-		if ctx != nil && ctx.SemanticScores != nil {
-			if s, ok := ctx.SemanticScores[value]; ok {
-				score = s
-			}
+	r.RegisterContext("semantic-similarity", func(cctx context.Context, value, output string, ctx *types.AssertionContext) types.AssertionResult {
+		score, err := semanticScore(cctx, value, output, ctx)
+		if err != nil {
+			return errorResult("semantic-similarity", value, err.Error())
 		}
 		threshold := 0.85
 		if ctx != nil && ctx.Threshold > 0 {
@@ -143,12 +385,56 @@ func initDefaultRegistry() *AssertionRegistry {
 		}
 		passed := score >= threshold
 		msg := fmt.Sprintf("%s semantic similarity %.2f vs threshold %.2f", boolPrefix(passed), score, threshold)
-		return types.AssertionResult{Type: "semantic-similarity", Value: value, Passed: passed, Message: msg}
+		return types.AssertionResult{Type: "semantic-similarity", Value: value, Passed: passed, Message: msg, Expected: fmt.Sprintf(">= %.2f", threshold), Score: &score}
 	})
 
+	// JSON assertions, see jsonpath.go
+	r.Register("is-json", assertIsJSON)
+	r.Register("json-path-equals", assertJSONPathEquals)
+	r.Register("json-path-contains", assertJSONPathContains)
+	r.Register("json-path-matches", assertJSONPathMatches)
+	r.Register("json-path-length", assertJSONPathLength)
+
+	// Metric assertions, see metrics.go
+	r.Register("word-count", metricAssertion("word-count", "Word count", countWords))
+	r.Register("char-count", metricAssertion("char-count", "Character count", countChars))
+	r.Register("sentence-count", metricAssertion("sentence-count", "Sentence count", countSentences))
+	r.Register("line-count", metricAssertion("line-count", "Line count", countLines))
+	r.Register("bullet-count", metricAssertion("bullet-count", "Bullet count", countBullets))
+
+	// Markdown assertions, see markdown.go
+	r.Register("has-heading", assertHasHeading)
+	r.Register("heading-count", metricAssertion("heading-count", "Heading count", countHeadings))
+	r.Register("max-heading-depth", assertMaxHeadingDepth)
+	r.Register("list-count", metricAssertion("list-count", "List count", countLists))
+	r.Register("list-item-count", metricAssertion("list-item-count", "List item count", countListItems))
+	r.Register("has-table", assertHasTable)
+
+	// Code block assertions, see codeblock.go
+	r.Register("valid-code", assertValidCode)
+	r.Register("code-block-lang", assertCodeBlockLang)
+	r.Register("code-block-count", assertCodeBlockCount)
+
+	// Lexical similarity against the reference output, see lexical.go
+	for _, name := range []string{"levenshtein", "rouge-1", "rouge-l", "bleu", "jaccard"} {
+		r.Register(name, lexicalAssertion(name))
+	}
+
+	// judge, see judge.go
+	r.RegisterContext("judge", assertJudge)
+
+	// expr, see expr.go
+	r.RegisterContext("expr", assertExpr)
+
 	return r
 }
 
+// DefaultAssertionRegistry returns the registry used by RunAssertions and
+// RegisterAssertion. Use Clone to derive a scoped registry from it.
+func DefaultAssertionRegistry() *AssertionRegistry {
+	return defaultRegistry
+}
+
 // RunAssertions executes a list of assertions against the provided output string.
 //
 // Parameters:
@@ -163,11 +449,35 @@ func RunAssertions(output string, assertions []types.Assertion, ctx *types.Asser
 	return defaultRegistry.RunAll(output, assertions, ctx)
 }
 
+// RunAssertionsContext is like RunAssertions but stops assertions that are
+// still running once ctx is cancelled.
+func RunAssertionsContext(ctx context.Context, output string, assertions []types.Assertion, actx *types.AssertionContext) []types.AssertionResult {
+	return defaultRegistry.RunAllContext(ctx, output, assertions, actx)
+}
+
+// RunAssertionsWith is like RunAssertions but looks assertions up in the
+// given registry. A nil registry uses the default registry.
+func RunAssertionsWith(registry *AssertionRegistry, output string, assertions []types.Assertion, ctx *types.AssertionContext) []types.AssertionResult {
+	if registry == nil {
+		registry = defaultRegistry
+	}
+	return registry.RunAll(output, assertions, ctx)
+}
+
 /**
  * Public API to run a single assertion
  */
 func RunAssertion(output string, assertion types.Assertion, ctx *types.AssertionContext) (types.AssertionResult, error) {
-	return defaultRegistry.Run(assertion.Type, assertion.Value, output, ctx)
+	return defaultRegistry.runAssertion(context.Background(), assertion, output, ctx)
+}
+
+// RunAssertionWith is like RunAssertion but looks the assertion up in the
+// given registry. A nil registry uses the default registry.
+func RunAssertionWith(registry *AssertionRegistry, output string, assertion types.Assertion, ctx *types.AssertionContext) (types.AssertionResult, error) {
+	if registry == nil {
+		registry = defaultRegistry
+	}
+	return registry.runAssertion(context.Background(), assertion, output, ctx)
 }
 
 /**
@@ -177,6 +487,22 @@ func RegisterAssertion(name string, fn AssertionFn) error {
 	return defaultRegistry.Register(name, fn)
 }
 
+// RegisterAssertionContext registers an assertion that takes a context in
+// the default registry.
+func RegisterAssertionContext(name string, fn ContextAssertionFn) error {
+	return defaultRegistry.RegisterContext(name, fn)
+}
+
+// OverrideAssertion replaces an assertion in the default registry.
+func OverrideAssertion(name string, fn AssertionFn) {
+	defaultRegistry.Override(name, fn)
+}
+
+// UnregisterAssertion removes an assertion from the default registry.
+func UnregisterAssertion(name string) error {
+	return defaultRegistry.Unregister(name)
+}
+
 func normalizeText(s string) string {
 	s = strings.ToLower(s)
 	s = strings.ReplaceAll(s, "-", " ")
@@ -189,12 +515,45 @@ func normalizeText(s string) string {
 	return s
 }
 
-func parseRegex(pattern string) (string, string) {
-	if strings.HasPrefix(pattern, "/") && strings.LastIndex(pattern, "/") > 0 {
-		lastSlash := strings.LastIndex(pattern, "/")
-		return pattern[1:lastSlash], pattern[lastSlash+1:]
+// normalizedSpans returns the spans of output whose normalized text equals
+// the already normalized value, the way contains compares them.
+func normalizedSpans(output, normalizedValue string) []types.Span {
+	if strings.TrimSpace(normalizedValue) == "" {
+		return nil
+	}
+
+	// Normalize the output like normalizeText, remembering the byte range
+	// of the original rune behind every normalized byte
+	var sb strings.Builder
+	var starts, ends []int
+	for i, r := range output {
+		_, size := utf8.DecodeRuneInString(output[i:])
+		if r == '-' {
+			r = ' '
+		}
+		r = unicode.ToLower(r)
+		if !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.IsSpace(r) {
+			continue
+		}
+		n, _ := sb.WriteRune(r)
+		for range n {
+			starts = append(starts, i)
+			ends = append(ends, i+size)
+		}
+	}
+
+	normalized := sb.String()
+	var spans []types.Span
+	for offset := 0; ; {
+		idx := strings.Index(normalized[offset:], normalizedValue)
+		if idx < 0 {
+			break
+		}
+		start, end := offset+idx, offset+idx+len(normalizedValue)
+		spans = append(spans, types.Span{Start: starts[start], End: ends[end-1]})
+		offset = end
 	}
-	return pattern, ""
+	return spans
 }
 
 func passFailMsg(passed bool, okFmt, failFmt, val string) string {
@@ -1,8 +1,14 @@
 package specform
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/specform/specform/sdk/go/specform/types"
 	"github.com/stretchr/testify/require"
@@ -22,6 +28,7 @@ func TestRunAssertions(t *testing.T) {
 		}
 	})
 	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, UnregisterAssertion("starts-with")) })
 
 	tests := []struct {
 		name       string
@@ -98,3 +105,243 @@ func TestRunAssertions(t *testing.T) {
 		})
 	}
 }
+
+func TestAssertionRegistry_OverrideAndUnregister(t *testing.T) {
+	registry := DefaultAssertionRegistry().Clone()
+
+	registry.Override("contains", func(val, out string, _ *types.AssertionContext) types.AssertionResult {
+		return types.AssertionResult{Type: "contains", Value: val, Passed: strings.Contains(out, val)}
+	})
+	results := RunAssertionsWith(registry, "Real-time", []types.Assertion{{Type: "contains", Value: "real time"}}, nil)
+	require.False(t, results[0].Passed)
+
+	// The default registry keeps the built-in
+	results = RunAssertions("Real-time", []types.Assertion{{Type: "contains", Value: "real time"}}, nil)
+	require.True(t, results[0].Passed)
+
+	require.NoError(t, registry.Unregister("equals"))
+	require.False(t, registry.Has("equals"))
+	require.True(t, DefaultAssertionRegistry().Has("equals"))
+	require.Error(t, registry.Unregister("equals"))
+
+	_, err := RunAssertionWith(registry, "anything", types.Assertion{Type: "equals", Value: "anything"}, nil)
+	require.Error(t, err)
+}
+
+func TestAssertionRegistry_ConcurrentRegisterAndRun(t *testing.T) {
+	registry := DefaultAssertionRegistry().Clone()
+	assertions := []types.Assertion{{Type: "contains", Value: "hook"}}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := range 8 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprintf("custom-%d", i)
+			if err := registry.Register(name, func(val, _ string, _ *types.AssertionContext) types.AssertionResult {
+				return types.AssertionResult{Type: name, Value: val, Passed: true}
+			}); err != nil {
+				errs <- err
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if results := registry.RunAll("webhook", assertions, nil); !results[0].Passed {
+					errs <- fmt.Errorf("contains failed: %s", results[0].Message)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	require.Len(t, registry.Names(), len(DefaultAssertionRegistry().Names())+8)
+}
+
+func TestRunAssertions_Negation(t *testing.T) {
+	results := RunAssertions("All good", []types.Assertion{
+		{Type: "not-contains", Value: "error"},
+		{Type: "not-contains", Value: "good"},
+		{Type: "not-matches", Value: "/[unterminated/"},
+		{Type: "not-unknown", Value: "x"},
+	}, nil)
+
+	require.True(t, results[0].Passed)
+	require.Equal(t, "not-contains", results[0].Type)
+	require.True(t, strings.HasPrefix(results[0].Message, "✔ Not: "))
+	require.False(t, results[1].Passed)
+
+	// Errors are not turned into passes
+	require.False(t, results[2].Passed)
+	require.NotEmpty(t, results[2].Error)
+	require.False(t, results[3].Passed)
+	require.NotEmpty(t, results[3].Error)
+}
+
+func TestRunAssertions_Groups(t *testing.T) {
+	anyOf := types.Assertion{Type: "any-of", Assertions: []types.Assertion{
+		{Type: "contains", Value: "webhook"},
+		{Type: "contains", Value: "callback"},
+	}}
+	allOf := types.Assertion{Type: "all-of", Assertions: []types.Assertion{
+		{Type: "contains", Value: "webhook"},
+		{Type: "not-contains", Value: "polling"},
+	}}
+
+	results := RunAssertions("Register a callback URL", []types.Assertion{anyOf, allOf}, nil)
+	require.True(t, results[0].Passed)
+	require.Equal(t, "✔ any-of: 1 of 2 passed", results[0].Message)
+	require.Len(t, results[0].Results, 2)
+	require.False(t, results[0].Results[0].Passed)
+
+	require.False(t, results[1].Passed)
+	require.Equal(t, "✘ all-of: 1 of 2 passed", results[1].Message)
+
+	nested := types.Assertion{Type: "not-all-of", Assertions: allOf.Assertions}
+	results = RunAssertions("Use polling", []types.Assertion{nested}, nil)
+	require.True(t, results[0].Passed)
+}
+
+func TestRunAssertions_ResultDetails(t *testing.T) {
+	output := "Use a Real-time webhook. Real time updates, no polling!"
+	results := RunAssertions(output, []types.Assertion{
+		{Type: "contains", Value: "real time"},
+		{Type: "matches", Value: "/w\\w+k/"},
+		{Type: "equals", Value: "Use a webhook"},
+		{Type: "word-count", Value: "< 20"},
+		{Type: "equals", Value: output},
+	}, nil)
+
+	contains := results[0]
+	require.Equal(t, "real time", contains.Expected)
+	require.Equal(t, []types.Span{{Start: 6, End: 15}, {Start: 25, End: 34}}, contains.Spans)
+	require.Equal(t, "Real-time", output[contains.Spans[0].Start:contains.Spans[0].End])
+
+	matches := results[1]
+	require.Equal(t, "webhook", matches.Actual)
+	require.Equal(t, []types.Span{{Start: 16, End: 23}}, matches.Spans)
+
+	equals := results[2]
+	require.False(t, equals.Passed)
+	require.Equal(t, output, equals.Actual)
+	require.Contains(t, equals.Diff, "-Use a webhook\n+"+output+"\n")
+	require.Empty(t, results[4].Actual, "passing equals does not copy the output")
+
+	wordCount := results[3]
+	require.Equal(t, "< 20", wordCount.Expected)
+	require.Equal(t, "9", wordCount.Actual)
+
+	for _, r := range results {
+		require.Positive(t, r.Duration)
+	}
+}
+
+func TestAssertionResult_JSONOmitsEmptyDetails(t *testing.T) {
+	data, err := json.Marshal(types.AssertionResult{Type: "contains", Value: "x", Passed: true, Message: "✔ ok"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"contains","value":"x","passed":true,"message":"✔ ok"}`, string(data))
+}
+
+func TestRunAssertions_RecoversFromPanics(t *testing.T) {
+	registry := DefaultAssertionRegistry().Clone()
+	require.NoError(t, registry.Register("explodes", func(string, string, *types.AssertionContext) types.AssertionResult {
+		panic("boom")
+	}))
+
+	results := registry.RunAll("webhook", []types.Assertion{
+		{Type: "explodes", Value: "x"},
+		{Type: "not-explodes", Value: "x"},
+		{Type: "contains", Value: "webhook"},
+	}, &types.AssertionContext{Timeout: time.Second})
+
+	require.False(t, results[0].Passed)
+	require.Equal(t, "Assertion panicked: boom", results[0].Error)
+	require.False(t, results[1].Passed)
+	require.True(t, results[2].Passed)
+}
+
+func TestRunAssertions_Timeouts(t *testing.T) {
+	registry := DefaultAssertionRegistry().Clone()
+	require.NoError(t, registry.RegisterContext("waits", func(ctx context.Context, value, _ string, _ *types.AssertionContext) types.AssertionResult {
+		<-ctx.Done()
+		return types.AssertionResult{Type: "waits", Value: value, Passed: true}
+	}))
+	block := make(chan struct{})
+	defer close(block)
+	require.NoError(t, registry.Register("ignores-context", func(value, _ string, _ *types.AssertionContext) types.AssertionResult {
+		<-block
+		return types.AssertionResult{Type: "ignores-context", Value: value, Passed: true}
+	}))
+
+	results := registry.RunAll("output", []types.Assertion{
+		{Type: "waits", Value: "default timeout"},
+		{Type: "ignores-context", Value: "own timeout", Timeout: 10 * time.Millisecond},
+		{Type: "all-of", Timeout: 10 * time.Millisecond, Assertions: []types.Assertion{{Type: "waits"}}},
+	}, &types.AssertionContext{Timeout: 20 * time.Millisecond})
+
+	for _, r := range results {
+		require.False(t, r.Passed)
+	}
+	require.Equal(t, "Assertion timed out", results[0].Error)
+	require.Equal(t, "Assertion timed out", results[1].Error)
+	require.Equal(t, "Assertion timed out", results[2].Results[0].Error)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results = registry.RunAllContext(ctx, "output", []types.Assertion{{Type: "contains", Value: "output"}}, nil)
+	require.Contains(t, results[0].Error, "Assertion cancelled")
+}
+
+func TestRunAssertions_Concurrency(t *testing.T) {
+	registry := DefaultAssertionRegistry().Clone()
+
+	var running, peak atomic.Int32
+	require.NoError(t, registry.Register("slow", func(value, _ string, _ *types.AssertionContext) types.AssertionResult {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return types.AssertionResult{Type: "slow", Value: value, Passed: true}
+	}))
+
+	var assertions []types.Assertion
+	for i := range 8 {
+		assertions = append(assertions, types.Assertion{Type: "slow", Value: fmt.Sprint(i)})
+	}
+
+	results := registry.RunAll("output", assertions, &types.AssertionContext{Concurrency: 4})
+	require.Len(t, results, 8)
+	for i, r := range results {
+		require.Equal(t, fmt.Sprint(i), r.Value)
+		require.True(t, r.Passed)
+	}
+	require.Equal(t, int32(4), peak.Load())
+}
+
+func TestRunAssertions_Expr(t *testing.T) {
+	ctx := &types.AssertionContext{Inputs: map[string]string{"product": "Specform"}}
+	results := RunAssertions("Specform renders prompts.", []types.Assertion{
+		{Type: "expr", Value: `len(words(output)) < 100 && contains(output, inputs.product)`},
+		{Type: "not-expr", Value: `endsWith(output, "!")`},
+		{Type: "expr", Value: `len(output)`},
+		{Type: "expr", Value: `inputs.missing == ""`},
+	}, ctx)
+
+	require.True(t, results[0].Passed, results[0].Message)
+	require.Equal(t, "✔ Expression is true: len(words(output)) < 100 && contains(output, inputs.product)", results[0].Message)
+	require.True(t, results[1].Passed, results[1].Message)
+	require.Equal(t, "expression must be a bool, got number", results[2].Error)
+	require.Contains(t, results[3].Error, "Expression failed: column 1: input missing has no value")
+}
@@ -0,0 +1,108 @@
+package specform
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+)
+
+// BatchRow is a single set of inputs in a batch render.
+type BatchRow struct {
+	Index  int               // position of the row in the batch
+	ID     string            // caller supplied row ID
+	Inputs map[string]string // inputs for this row
+	Err    error             // set when the row could not be read; it fails without rendering
+}
+
+// BatchResult is the outcome of rendering a single batch row. A failed row
+// has Err and Error set instead of Output.
+type BatchResult struct {
+	Index  int               `json:"-"`
+	ID     string            `json:"id"`
+	Inputs map[string]string `json:"inputs"`
+	Output string            `json:"output,omitempty"`
+	Error  string            `json:"error,omitempty"`
+	Err    error             `json:"-"`
+}
+
+// BatchOptions configures RenderBatch.
+type BatchOptions struct {
+	RenderOptions
+	Concurrency int // number of rows rendered at once, defaults to GOMAXPROCS
+}
+
+// RenderBatch renders a compiled prompt for every row received on rows and
+// streams the results. Rows are rendered concurrently, so results arrive in
+// completion order; use BatchResult.Index to restore the input order. A row
+// that fails to render produces a result with Err set and does not stop the
+// batch. The results channel is closed once rows is closed and drained, or
+// ctx is cancelled.
+func RenderBatch(ctx context.Context, prompt *types.CompiledPrompt, rows <-chan BatchRow, opts *BatchOptions) (<-chan BatchResult, error) {
+	prepared, err := PreparePrompt(prompt)
+	if err != nil {
+		return nil, err
+	}
+	return prepared.RenderBatch(ctx, rows, opts), nil
+}
+
+// RenderBatch renders the prepared prompt for every row received on rows.
+// See RenderBatch for details.
+func (p *PreparedPrompt) RenderBatch(ctx context.Context, rows <-chan BatchRow, opts *BatchOptions) <-chan BatchResult {
+	concurrency := runtime.GOMAXPROCS(0)
+	var renderOpts *RenderOptions
+	if opts != nil {
+		// Rows are rendered concurrently, so there is no single usage or
+		// set of escapes to report
+		shared := opts.RenderOptions
+		shared.Usage, shared.Escapes = nil, nil
+		renderOpts = &shared
+		if opts.Concurrency > 0 {
+			concurrency = opts.Concurrency
+		}
+	}
+
+	results := make(chan BatchResult, concurrency)
+
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var row BatchRow
+				var ok bool
+				select {
+				case <-ctx.Done():
+					return
+				case row, ok = <-rows:
+					if !ok {
+						return
+					}
+				}
+
+				result := BatchResult{Index: row.Index, ID: row.ID, Inputs: row.Inputs, Err: row.Err}
+				if result.Err == nil {
+					result.Output, result.Err = p.Render(row.Inputs, renderOpts)
+				}
+				if result.Err != nil {
+					result.Error = result.Err.Error()
+				}
+
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
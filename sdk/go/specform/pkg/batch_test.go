@@ -0,0 +1,85 @@
+package specform
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderBatch(t *testing.T) {
+	scenario := &types.CompiledPrompt{
+		Prompt: "Summarize {{article}} in a {{tone}} tone.",
+		Inputs: []string{"article", "tone"},
+		Values: map[string]string{"tone": "casual"},
+	}
+
+	rows := make(chan BatchRow)
+	go func() {
+		defer close(rows)
+		for i := 0; i < 20; i++ {
+			inputs := map[string]string{"article": fmt.Sprintf("article %d", i)}
+			if i%5 == 0 {
+				inputs = map[string]string{} // missing article
+			}
+			rows <- BatchRow{Index: i, ID: fmt.Sprintf("row-%d", i), Inputs: inputs}
+		}
+	}()
+
+	results, err := RenderBatch(context.Background(), scenario, rows, &BatchOptions{
+		RenderOptions: RenderOptions{Strict: true},
+		Concurrency:   4,
+	})
+	require.NoError(t, err)
+
+	seen := map[int]BatchResult{}
+	for r := range results {
+		seen[r.Index] = r
+	}
+
+	require.Len(t, seen, 20)
+	for i, r := range seen {
+		require.Equal(t, fmt.Sprintf("row-%d", i), r.ID)
+		if i%5 == 0 {
+			require.Error(t, r.Err)
+			require.Contains(t, r.Error, "missing required inputs: article")
+			continue
+		}
+		require.NoError(t, r.Err)
+		require.Equal(t, fmt.Sprintf("Summarize article %d in a casual tone.", i), r.Output)
+	}
+}
+
+func TestRenderBatch_Cancel(t *testing.T) {
+	scenario := &types.CompiledPrompt{Prompt: "{{article}}"}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	rows := make(chan BatchRow) // never closed
+	results, err := RenderBatch(ctx, scenario, rows, nil)
+	require.NoError(t, err)
+
+	cancel()
+	for range results {
+	}
+}
+
+func TestRenderBatch_RowErrors(t *testing.T) {
+	scenario := &types.CompiledPrompt{Prompt: "{{article}}"}
+	rows := make(chan BatchRow, 2)
+	rows <- BatchRow{Index: 0, ID: "1", Inputs: map[string]string{"article": "ok"}}
+	rows <- BatchRow{Index: 1, ID: "2", Err: fmt.Errorf("invalid JSON on batch line 2")}
+	close(rows)
+
+	results, err := RenderBatch(context.Background(), scenario, rows, &BatchOptions{Concurrency: 1})
+	require.NoError(t, err)
+
+	seen := map[int]BatchResult{}
+	for r := range results {
+		seen[r.Index] = r
+	}
+	require.Equal(t, "ok", seen[0].Output)
+	require.Empty(t, seen[1].Output)
+	require.Equal(t, "invalid JSON on batch line 2", seen[1].Error)
+}
@@ -0,0 +1,167 @@
+package specform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+)
+
+// truncationMarker replaces the text dropped by the middle-out strategy.
+const truncationMarker = "…"
+
+// budgetEntry is a referenced input and its estimated tokens.
+type budgetEntry struct {
+	name     string
+	tokens   []string
+	overhead int // tokens of the delimiters added after budgeting
+	opts     types.InputOptions
+}
+
+// applyBudget truncates the referenced inputs in values so they fit their
+// per-input max_tokens and the prompt's max_input_tokens. Values are budgeted
+// after escaping, with the tokens of their delimiters reserved. Inputs without a
+// truncation strategy are never shortened; if the budget cannot be met the
+// render is refused with an error. When trace is set the token counts and any
+// truncations are recorded on it.
+func (p *PreparedPrompt) applyBudget(values map[string]string, opts *RenderOptions, trace *types.RenderTrace) error {
+	limit := p.maxInputTokens
+	if opts != nil && opts.MaxInputTokens > 0 {
+		limit = opts.MaxInputTokens
+	}
+
+	if limit == 0 && !p.hasInputLimits && trace == nil {
+		return nil
+	}
+
+	tok := TokenizerForModel(p.modelFor(opts))
+
+	var entries []*budgetEntry
+	for _, name := range p.inputRefs() {
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+		entry := &budgetEntry{name: name, tokens: tok.Encode(value), overhead: p.delimiterTokens(name, tok), opts: p.inputOptions[name]}
+
+		// Per-input limits are applied first
+		if n := entry.opts.MaxTokens; n > 0 && entry.size() > n {
+			if !canTruncate(entry.opts.Truncate) || entry.overhead >= n {
+				return fmt.Errorf("input %s uses %d tokens, exceeding its limit of %d", name, entry.size(), n)
+			}
+			if err := entry.truncate(n-entry.overhead, values, trace); err != nil {
+				return err
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	if limit > 0 && sumTokens(entries) > limit {
+		if err := fitBudget(entries, limit, values, trace); err != nil {
+			return err
+		}
+	}
+
+	if trace != nil {
+		trace.InputTokens = sumTokens(entries)
+	}
+	return nil
+}
+
+// fitBudget shares the budget between the truncatable inputs. Smaller inputs
+// are kept whole where possible and the remainder is split evenly between the
+// larger ones.
+func fitBudget(entries []*budgetEntry, limit int, values map[string]string, trace *types.RenderTrace) error {
+	var flexible []*budgetEntry
+	fixed := 0
+	for _, e := range entries {
+		if canTruncate(e.opts.Truncate) {
+			flexible = append(flexible, e)
+			fixed += e.overhead
+		} else {
+			fixed += e.size()
+		}
+	}
+
+	available := limit - fixed
+	if len(flexible) == 0 || available < 0 {
+		return fmt.Errorf("inputs use %d tokens, exceeding max_input_tokens of %d", sumTokens(entries), limit)
+	}
+
+	sort.SliceStable(flexible, func(i, j int) bool {
+		return len(flexible[i].tokens) < len(flexible[j].tokens)
+	})
+
+	for i, e := range flexible {
+		share := available / (len(flexible) - i)
+		if len(e.tokens) > share {
+			if err := e.truncate(share, values, trace); err != nil {
+				return err
+			}
+		}
+		available -= len(e.tokens)
+	}
+
+	return nil
+}
+
+// truncate shortens the entry to keep tokens using its strategy, updating
+// the rendered value.
+func (e *budgetEntry) truncate(keep int, values map[string]string, trace *types.RenderTrace) error {
+	strategy := e.opts.Truncate
+	if !canTruncate(strategy) {
+		return fmt.Errorf("input %s uses %d tokens, exceeding its limit of %d", e.name, len(e.tokens), keep)
+	}
+
+	before := len(e.tokens)
+	switch strategy {
+	case types.TruncateHead:
+		e.tokens = e.tokens[:keep]
+	case types.TruncateTail:
+		e.tokens = e.tokens[before-keep:]
+	case types.TruncateMiddleOut:
+		if keep < 3 {
+			e.tokens = e.tokens[:keep]
+			break
+		}
+		head := keep / 2
+		tail := keep - head - 1
+		kept := make([]string, 0, keep)
+		kept = append(kept, e.tokens[:head]...)
+		kept = append(kept, truncationMarker)
+		e.tokens = append(kept, e.tokens[before-tail:]...)
+	}
+
+	values[e.name] = strings.Join(e.tokens, "")
+	if trace != nil {
+		trace.Truncations = append(trace.Truncations, types.TruncationTrace{
+			Input:    e.name,
+			Strategy: strategy,
+			Tokens:   before,
+			Kept:     len(e.tokens),
+		})
+	}
+	return nil
+}
+
+func canTruncate(strategy string) bool {
+	switch strategy {
+	case types.TruncateHead, types.TruncateTail, types.TruncateMiddleOut:
+		return true
+	}
+	return false
+}
+
+// size returns the tokens of the entry as rendered, delimiters included.
+func (e *budgetEntry) size() int {
+	return len(e.tokens) + e.overhead
+}
+
+func sumTokens(entries []*budgetEntry) int {
+	total := 0
+	for _, e := range entries {
+		total += e.size()
+	}
+	return total
+}
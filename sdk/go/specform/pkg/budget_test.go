@@ -0,0 +1,153 @@
+package specform
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountTokens(t *testing.T) {
+	require.Equal(t, 0, CountTokens("", "gpt-4"))
+	require.Equal(t, 4, CountTokens("Hello, world!", "gpt-4"))
+
+	text := "Webhooks enable real-time communication between systems."
+	tok := TokenizerForModel("claude-3-5-sonnet")
+	require.Equal(t, text, strings.Join(tok.Encode(text), ""))
+	require.Equal(t, len(tok.Encode(text)), tok.Count(text))
+
+	// Non-latin scripts are roughly a token per character
+	require.Equal(t, 4, CountTokens("你好世界", "gpt-4o"))
+}
+
+func TestRenderPrompt_InputBudget(t *testing.T) {
+	article := strings.TrimSpace(strings.Repeat("alpha beta gamma delta ", 50))
+
+	tests := []struct {
+		name     string
+		strategy string
+		check    func(t *testing.T, out string)
+	}{
+		{"head", types.TruncateHead, func(t *testing.T, out string) {
+			require.True(t, strings.HasPrefix(out, "alpha beta"))
+		}},
+		{"tail", types.TruncateTail, func(t *testing.T, out string) {
+			require.True(t, strings.HasSuffix(out, "gamma delta"))
+		}},
+		{"middle-out", types.TruncateMiddleOut, func(t *testing.T, out string) {
+			require.True(t, strings.HasPrefix(out, "alpha beta"))
+			require.True(t, strings.HasSuffix(out, "gamma delta"))
+			require.Contains(t, out, truncationMarker)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := &types.CompiledPrompt{
+				Prompt:         "{{article}}",
+				Inputs:         []string{"article"},
+				Model:          "gpt-4",
+				MaxInputTokens: 20,
+				InputOptions:   map[string]types.InputOptions{"article": {Truncate: tt.strategy}},
+			}
+
+			out, trace, err := RenderWithTrace(scenario, map[string]string{"article": article}, nil)
+			require.NoError(t, err)
+			require.Equal(t, 20, trace.InputTokens)
+			require.LessOrEqual(t, CountTokens(out, "gpt-4"), 20)
+			require.Len(t, trace.Truncations, 1)
+			require.Equal(t, 200, trace.Truncations[0].Tokens)
+			tt.check(t, out)
+		})
+	}
+}
+
+func TestRenderPrompt_InputBudgetShared(t *testing.T) {
+	scenario := &types.CompiledPrompt{
+		Prompt:         "{{question}}\n{{context}}\n{{notes}}",
+		Inputs:         []string{"question", "context", "notes"},
+		MaxInputTokens: 30,
+		InputOptions: map[string]types.InputOptions{
+			"context": {Truncate: types.TruncateHead},
+			"notes":   {Truncate: types.TruncateTail},
+		},
+	}
+	inputs := map[string]string{
+		"question": "What are webhooks?",
+		"context":  strings.Repeat(" word", 100),
+		"notes":    strings.Repeat(" note", 100),
+	}
+
+	_, trace, err := RenderWithTrace(scenario, inputs, nil)
+	require.NoError(t, err)
+	require.Equal(t, 30, trace.InputTokens)
+	require.Len(t, trace.Truncations, 2)
+}
+
+func TestRenderPrompt_InputBudgetRefused(t *testing.T) {
+	scenario := &types.CompiledPrompt{
+		Prompt:       "{{article}}",
+		Inputs:       []string{"article"},
+		InputOptions: map[string]types.InputOptions{"article": {MaxTokens: 5, Truncate: types.TruncateRefuse}},
+	}
+
+	_, err := RenderPrompt(scenario, map[string]string{"article": strings.Repeat(" word", 20)}, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "input article uses 20 tokens, exceeding its limit of 5")
+
+	scenario.InputOptions = nil
+	_, err = RenderPrompt(scenario, map[string]string{"article": strings.Repeat(" word", 20)}, &RenderOptions{MaxInputTokens: 5})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "exceeding max_input_tokens of 5")
+}
+
+func TestRenderPrompt_InputBudgetCountsDelimiters(t *testing.T) {
+	scenario := &types.CompiledPrompt{
+		Prompt:         "{{article}}",
+		Inputs:         []string{"article"},
+		Model:          "gpt-4",
+		MaxInputTokens: 30,
+		InputOptions: map[string]types.InputOptions{
+			"article": {Truncate: types.TruncateHead, Escape: []string{types.EscapeDelimit}},
+		},
+	}
+	article := strings.Repeat("alpha beta gamma delta ", 50)
+
+	out, trace, err := RenderWithTrace(scenario, map[string]string{"article": article}, nil)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(out, "<article>\nalpha beta"))
+	require.True(t, strings.HasSuffix(out, "\n</article>"), "truncation keeps the closing delimiter")
+	require.LessOrEqual(t, CountTokens(out, "gpt-4"), 30)
+	require.Equal(t, 30, trace.InputTokens)
+
+	// An input that can't be truncated is refused when its delimiters don't fit
+	scenario.InputOptions["article"] = types.InputOptions{MaxTokens: 4, Escape: []string{types.EscapeDelimit}}
+	_, err = RenderPrompt(scenario, map[string]string{"article": "alpha"}, nil)
+	require.ErrorContains(t, err, "exceeding its limit of 4")
+}
+
+func TestRenderPrompt_ReportsUsage(t *testing.T) {
+	scenario := &types.CompiledPrompt{
+		Prompt:         "Summarize: {{article}}",
+		Inputs:         []string{"article"},
+		Model:          "gpt-4",
+		MaxInputTokens: 20,
+		InputOptions:   map[string]types.InputOptions{"article": {Truncate: types.TruncateHead}},
+	}
+	article := strings.Repeat("alpha beta gamma delta ", 50)
+
+	var usage RenderUsage
+	out, err := RenderPrompt(scenario, map[string]string{"article": article}, &RenderOptions{Usage: &usage})
+	require.NoError(t, err)
+	require.Equal(t, 20, usage.InputTokens)
+	require.Equal(t, CountTokens(out, "gpt-4"), usage.PromptTokens)
+
+	var streamed RenderUsage
+	var sb strings.Builder
+	err = RenderTo(context.Background(), &sb, scenario, map[string]string{"article": article}, &RenderOptions{Usage: &streamed})
+	require.NoError(t, err)
+	require.Equal(t, out, sb.String())
+	require.Equal(t, usage, streamed)
+}
@@ -0,0 +1,38 @@
+package specform
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// maxCachedCompilations bounds the number of entries kept by each
+// compileCache.
+const maxCachedCompilations = 1024
+
+// compileCache memoizes the compiled form of assertion values such as
+// regexes and expressions. It stops adding entries once it holds
+// maxCachedCompilations, so specs with ever-changing values cannot grow it
+// without bound. The zero value is ready to use.
+type compileCache[T any] struct {
+	entries sync.Map // source → T
+	size    atomic.Int32
+}
+
+// get returns the cached value for source, calling compile and caching the
+// result on a miss. Errors are not cached.
+func (c *compileCache[T]) get(source string, compile func(string) (T, error)) (T, error) {
+	if v, ok := c.entries.Load(source); ok {
+		return v.(T), nil
+	}
+
+	v, err := compile(source)
+	if err != nil {
+		return v, err
+	}
+	if c.size.Load() < maxCachedCompilations {
+		if _, loaded := c.entries.LoadOrStore(source, v); !loaded {
+			c.size.Add(1)
+		}
+	}
+	return v, nil
+}
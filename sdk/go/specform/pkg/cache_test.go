@@ -0,0 +1,42 @@
+package specform
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileCache(t *testing.T) {
+	var cache compileCache[int]
+	calls := 0
+	compile := func(s string) (int, error) {
+		calls++
+		if s == "bad" {
+			return 0, errors.New("bad source")
+		}
+		return strconv.Atoi(s)
+	}
+
+	for range 2 {
+		v, err := cache.get("42", compile)
+		require.NoError(t, err)
+		require.Equal(t, 42, v)
+	}
+	require.Equal(t, 1, calls)
+
+	// Errors are not cached
+	for range 2 {
+		_, err := cache.get("bad", compile)
+		require.EqualError(t, err, "bad source")
+	}
+	require.Equal(t, 3, calls)
+
+	// Once full, new values are compiled but not stored
+	for i := range maxCachedCompilations + 10 {
+		_, err := cache.get(strconv.Itoa(i+1000), compile)
+		require.NoError(t, err)
+	}
+	require.EqualValues(t, maxCachedCompilations, cache.size.Load())
+}
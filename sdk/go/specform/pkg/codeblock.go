@@ -0,0 +1,268 @@
+package specform
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/specform/specform/sdk/go/specform/internal"
+	"github.com/specform/specform/sdk/go/specform/types"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+	"gopkg.in/yaml.v3"
+)
+
+// Code block assertions check the fenced code blocks of the output, e.g.
+// `valid-code: go`, `code-block-lang: python` or `code-block-count: 1`.
+// Line numbers in messages count from the start of the output.
+
+// codeBlock is a fenced code block of the output.
+type codeBlock struct {
+	lang string // canonical language, see internal.CodeLanguage
+	tag  string // language tag as written after the fence
+	code string
+	line int // line of the output where the code starts, 0 if unknown
+	span types.Span
+}
+
+// codeSyntaxError is a parse error at a line of a code block.
+type codeSyntaxError struct {
+	line int // 1-based, 0 when unknown
+	msg  string
+}
+
+// codeValidators parse the code of a language and return the first syntax
+// error.
+var codeValidators = map[string]func(code string) *codeSyntaxError{
+	"go":   validateGo,
+	"json": validateJSON,
+	"yaml": validateYAML,
+}
+
+// parseCodeBlocks returns the fenced code blocks of the output in order.
+func parseCodeBlocks(output string) []codeBlock {
+	source := []byte(output)
+	root := markdownParser.Parse(text.NewReader(source))
+
+	var blocks []codeBlock
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		fence, ok := n.(*ast.FencedCodeBlock)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+
+		tag := string(fence.Language(source))
+		b := codeBlock{lang: internal.CodeLanguage(tag), tag: tag}
+		lines := fence.Lines()
+		var code strings.Builder
+		for i := 0; i < lines.Len(); i++ {
+			line := lines.At(i)
+			code.Write(line.Value(source))
+		}
+		b.code = code.String()
+
+		switch {
+		case lines.Len() > 0:
+			b.span = types.Span{Start: lines.At(0).Start, End: lines.At(lines.Len() - 1).Stop}
+			b.line = 1 + bytes.Count(source[:b.span.Start], []byte("\n"))
+		case fence.Info != nil:
+			// Empty block, the code would start on the line after the info
+			end := fence.Info.Segment.Stop
+			b.span = types.Span{Start: end, End: end}
+			b.line = 2 + bytes.Count(source[:end], []byte("\n"))
+		}
+
+		blocks = append(blocks, b)
+		return ast.WalkSkipChildren, nil
+	})
+	return blocks
+}
+
+// assertValidCode passes when the output has at least one code block tagged
+// with the language in the value and all of them parse.
+func assertValidCode(value, output string, _ *types.AssertionContext) types.AssertionResult {
+	lang := internal.CodeLanguage(value)
+	validate, ok := codeValidators[lang]
+	if !ok {
+		return errorResult("valid-code", value, fmt.Sprintf("Unsupported language %q, expected go, json or yaml", value))
+	}
+
+	result := types.AssertionResult{Type: "valid-code", Value: value, Expected: "valid " + lang}
+	var checked int
+	var failures []string
+	for _, b := range parseCodeBlocks(output) {
+		if b.lang != lang {
+			continue
+		}
+		checked++
+		if err := validate(b.code); err != nil {
+			failure := err.msg
+			if b.line > 0 && err.line > 0 {
+				failure = fmt.Sprintf("line %d: %s", b.line+err.line-1, err.msg)
+			}
+			failures = append(failures, failure)
+			result.Spans = append(result.Spans, b.span)
+		}
+	}
+
+	result.Passed = checked > 0 && len(failures) == 0
+	switch {
+	case checked == 0:
+		result.Message = fmt.Sprintf("✘ Output has no %s code block", lang)
+	case result.Passed:
+		result.Message = fmt.Sprintf("✔ %d %s code block(s) are valid", checked, lang)
+	default:
+		result.Actual = strings.Join(failures, "; ")
+		result.Message = fmt.Sprintf("✘ %d of %d %s code block(s) are invalid: %s", len(failures), checked, lang, failures[0])
+	}
+	return result
+}
+
+// assertCodeBlockLang passes when the output has code blocks and every one
+// is tagged with the language in the value.
+func assertCodeBlockLang(value, output string, _ *types.AssertionContext) types.AssertionResult {
+	lang := internal.CodeLanguage(value)
+	blocks := parseCodeBlocks(output)
+
+	result := types.AssertionResult{Type: "code-block-lang", Value: value, Expected: lang}
+	var wrong []string
+	for _, b := range blocks {
+		if b.lang != lang {
+			tag := b.tag
+			if tag == "" {
+				tag = "untagged"
+			}
+			if b.line > 0 {
+				tag += fmt.Sprintf(" at line %d", b.line)
+			}
+			wrong = append(wrong, tag)
+			result.Spans = append(result.Spans, b.span)
+		}
+	}
+
+	result.Passed = len(blocks) > 0 && len(wrong) == 0
+	switch {
+	case len(blocks) == 0:
+		result.Message = "✘ Output has no code blocks"
+	case result.Passed:
+		result.Message = fmt.Sprintf("✔ All %d code block(s) are tagged %s", len(blocks), value)
+	default:
+		result.Actual = strings.Join(wrong, ", ")
+		result.Message = fmt.Sprintf("✘ Expected code blocks tagged %s, found %s", value, result.Actual)
+	}
+	return result
+}
+
+// assertCodeBlockCount compares the number of code blocks, optionally only
+// those of a language, e.g. "1" or "go >= 2".
+func assertCodeBlockCount(value, output string, actx *types.AssertionContext) types.AssertionResult {
+	lang, cmp := "", strings.TrimSpace(value)
+	if first, rest, ok := strings.Cut(cmp, " "); ok {
+		if r := []rune(first)[0]; unicode.IsLetter(r) {
+			lang, cmp = internal.CodeLanguage(first), rest
+		}
+	}
+
+	label := "Code block count"
+	if lang != "" {
+		label = "Count of " + lang + " code blocks"
+	}
+	result := metricAssertion("code-block-count", label, func(output string) int {
+		count := 0
+		for _, b := range parseCodeBlocks(output) {
+			if lang == "" || b.lang == lang {
+				count++
+			}
+		}
+		return count
+	})(cmp, output, actx)
+	result.Value = value
+	return result
+}
+
+// validateGo parses Go code. Code without a package clause is parsed as
+// declarations, or failing that as the statements of a function body.
+func validateGo(code string) *codeSyntaxError {
+	fset := token.NewFileSet()
+	_, err := parser.ParseFile(fset, "", code, parser.SkipObjectResolution)
+	if err == nil {
+		return nil
+	}
+	if !strings.Contains(err.Error(), "expected 'package'") {
+		return goSyntaxError(err)
+	}
+
+	// The wrappers are added to the first line, so line numbers still match
+	_, declErr := parser.ParseFile(fset, "", "package p; "+code, parser.SkipObjectResolution)
+	if declErr == nil {
+		return nil
+	}
+	_, stmtErr := parser.ParseFile(fset, "", "package p; func _() { "+code+"\n}", parser.SkipObjectResolution)
+	if stmtErr == nil {
+		return nil
+	}
+
+	// Report the attempt that parsed furthest
+	declSyntax, stmtSyntax := goSyntaxError(declErr), goSyntaxError(stmtErr)
+	if stmtSyntax.line > declSyntax.line {
+		return stmtSyntax
+	}
+	return declSyntax
+}
+
+func goSyntaxError(err error) *codeSyntaxError {
+	var list scanner.ErrorList
+	if errors.As(err, &list) && len(list) > 0 {
+		return &codeSyntaxError{line: list[0].Pos.Line, msg: list[0].Msg}
+	}
+	return &codeSyntaxError{msg: err.Error()}
+}
+
+// validateJSON parses a single JSON value.
+func validateJSON(code string) *codeSyntaxError {
+	var v any
+	err := json.Unmarshal([]byte(code), &v)
+	if err == nil {
+		return nil
+	}
+
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) {
+		offset := min(int(syntax.Offset), len(code))
+		return &codeSyntaxError{line: 1 + strings.Count(code[:offset], "\n"), msg: syntax.Error()}
+	}
+	return &codeSyntaxError{msg: err.Error()}
+}
+
+// yamlLinePattern extracts the line from yaml.v3 error messages such as
+// "yaml: line 3: mapping values are not allowed in this context".
+var yamlLinePattern = regexp.MustCompile(`^yaml: line (\d+): `)
+
+// validateYAML parses one or more YAML documents.
+func validateYAML(code string) *codeSyntaxError {
+	dec := yaml.NewDecoder(strings.NewReader(code))
+	for {
+		var v any
+		err := dec.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			msg := err.Error()
+			if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+				line, _ := strconv.Atoi(m[1])
+				return &codeSyntaxError{line: line, msg: strings.TrimPrefix(msg, m[0])}
+			}
+			return &codeSyntaxError{msg: strings.TrimPrefix(msg, "yaml: ")}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package specform
+
+import (
+	"testing"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeBlockAssertions(t *testing.T) {
+	output := "Here is the handler:\n\n```go\nfunc handle(w http.ResponseWriter) {\n\tw.WriteHeader(200)\n}\n```\n\nAnd its config:\n\n```json\n{\n  \"port\": 8080,\n  \"debug\": true,\n}\n```\n\n```yaml\nroutes:\n  - /health\n```\n"
+	results := RunAssertions(output, []types.Assertion{
+		{Type: "valid-code", Value: "go"},
+		{Type: "valid-code", Value: "json"},
+		{Type: "valid-code", Value: "yml"},
+		{Type: "code-block-count", Value: "3"},
+		{Type: "code-block-count", Value: "golang == 1"},
+		{Type: "code-block-lang", Value: "go"},
+	}, nil)
+
+	require.True(t, results[0].Passed, results[0].Message)
+	require.False(t, results[1].Passed)
+	require.Equal(t, "✘ 1 of 1 json code block(s) are invalid: line 15: invalid character '}' looking for beginning of object key string", results[1].Message)
+	require.Len(t, results[1].Spans, 1)
+	require.True(t, results[2].Passed, results[2].Message)
+	require.True(t, results[3].Passed)
+	require.True(t, results[4].Passed, results[4].Message)
+	require.Equal(t, "golang == 1", results[4].Value)
+	require.False(t, results[5].Passed)
+	require.Equal(t, "✘ Expected code blocks tagged go, found json at line 12, yaml at line 19", results[5].Message)
+}
+
+func TestValidateGo(t *testing.T) {
+	tests := []struct {
+		code string
+		line int
+	}{
+		{"package main\n\nfunc main() {}\n", 0},
+		{"type User struct {\n\tName string\n}\n", 0},
+		{"x := 1\nfmt.Println(x)\n", 0},
+		{"func main() {\n\tfmt.Println(\"hi\"\n}\n", 2},
+		{"package main\n\nfunc main() {\n\tif {\n}\n", 4},
+	}
+	for _, tt := range tests {
+		err := validateGo(tt.code)
+		if tt.line == 0 {
+			require.Nil(t, err, tt.code)
+			continue
+		}
+		require.NotNil(t, err, tt.code)
+		require.Equal(t, tt.line, err.line, tt.code)
+	}
+}
+
+func TestValidateYAML(t *testing.T) {
+	require.Nil(t, validateYAML("a: 1\n---\nb: [1, 2]\n"))
+
+	err := validateYAML("name: api\nport: 80\nhost: db: 5432\n")
+	require.NotNil(t, err)
+	require.Equal(t, 3, err.line)
+	require.Equal(t, "mapping values are not allowed in this context", err.msg)
+}
+
+func TestCodeBlockAssertions_NoBlocks(t *testing.T) {
+	results := RunAssertions("No code here.", []types.Assertion{
+		{Type: "valid-code", Value: "go"},
+		{Type: "code-block-count", Value: "0"},
+		{Type: "code-block-lang", Value: "go"},
+		{Type: "valid-code", Value: "rust"},
+	}, nil)
+
+	require.Equal(t, "✘ Output has no go code block", results[0].Message)
+	require.True(t, results[1].Passed)
+	require.Equal(t, "✘ Output has no code blocks", results[2].Message)
+	require.NotEmpty(t, results[3].Error)
+}
@@ -0,0 +1,68 @@
+package specform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// comparison is a numeric check such as ">= 3" used by assertions that
+// measure something about the output.
+type comparison struct {
+	op string
+	n  float64
+}
+
+// comparisonOps lists the supported operators, longest first so that "<="
+// is not read as "<".
+var comparisonOps = []struct{ symbol, op string }{
+	{"==", "=="}, {"!=", "!="}, {"<=", "<="}, {">=", ">="},
+	{"≤", "<="}, {"≥", ">="}, {"≠", "!="},
+	{"=", "=="}, {"<", "<"}, {">", ">"},
+}
+
+// parseComparison parses expressions such as "<= 120", ">3" or "5". A bare
+// number means equality.
+func parseComparison(expr string) (comparison, error) {
+	expr = strings.TrimSpace(expr)
+	c := comparison{op: "=="}
+	for _, o := range comparisonOps {
+		if strings.HasPrefix(expr, o.symbol) {
+			c.op = o.op
+			expr = strings.TrimSpace(strings.TrimPrefix(expr, o.symbol))
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(expr, 64)
+	if err != nil {
+		return comparison{}, fmt.Errorf("invalid comparison %q, expected e.g. \"<= 120\"", expr)
+	}
+	c.n = n
+	return c, nil
+}
+
+func (c comparison) test(v float64) bool {
+	switch c.op {
+	case "!=":
+		return v != c.n
+	case "<":
+		return v < c.n
+	case "<=":
+		return v <= c.n
+	case ">":
+		return v > c.n
+	case ">=":
+		return v >= c.n
+	default:
+		return v == c.n
+	}
+}
+
+func (c comparison) String() string {
+	return c.op + " " + formatNumber(c.n)
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
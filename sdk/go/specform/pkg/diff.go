@@ -0,0 +1,130 @@
+package specform
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// diffLine is a line of an edit script: ' ' kept, '-' removed or '+' added.
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// unifiedDiff returns a line based unified diff turning expected into
+// actual, or "" when they are equal.
+func unifiedDiff(expected, actual string) string {
+	if expected == actual {
+		return ""
+	}
+
+	a, b := splitDiffLines(expected), splitDiffLines(actual)
+	script := diffLines(a, b)
+
+	var sb strings.Builder
+	sb.WriteString("--- expected\n+++ actual\n")
+
+	// Positions in a and b of every line of the script, 0 based
+	aPos, bPos := make([]int, len(script)+1), make([]int, len(script)+1)
+	for i, l := range script {
+		aPos[i+1], bPos[i+1] = aPos[i], bPos[i]
+		if l.op != '+' {
+			aPos[i+1]++
+		}
+		if l.op != '-' {
+			bPos[i+1]++
+		}
+	}
+
+	for start := 0; start < len(script); {
+		// Find the next change and extend the hunk while changes are close
+		first := start
+		for first < len(script) && script[first].op == ' ' {
+			first++
+		}
+		if first == len(script) {
+			break
+		}
+		last := first
+		for i := first; i < len(script) && i <= last+2*diffContext; i++ {
+			if script[i].op != ' ' {
+				last = i
+			}
+		}
+
+		from := max(first-diffContext, start)
+		to := min(last+diffContext+1, len(script))
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(aPos[from], aPos[to]-aPos[from]), hunkRange(bPos[from], bPos[to]-bPos[from]))
+		for _, l := range script[from:to] {
+			sb.WriteByte(l.op)
+			sb.WriteString(l.text)
+			sb.WriteByte('\n')
+		}
+		start = to
+	}
+	return sb.String()
+}
+
+// hunkRange formats the 1 based start line and length of a hunk side.
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+func splitDiffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines computes an edit script from the longest common subsequence of
+// the lines.
+func diffLines(a, b []string) []diffLine {
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	script := make([]diffLine, 0, max(len(a), len(b)))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			script = append(script, diffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			script = append(script, diffLine{'-', a[i]})
+			i++
+		default:
+			script = append(script, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		script = append(script, diffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		script = append(script, diffLine{'+', b[j]})
+	}
+	return script
+}
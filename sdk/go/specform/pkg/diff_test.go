@@ -0,0 +1,31 @@
+package specform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	require.Empty(t, unifiedDiff("same\ntext", "same\ntext"))
+
+	expected := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten"
+	actual := "one\n2\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven"
+	require.Equal(t, `--- expected
++++ actual
+@@ -1,5 +1,5 @@
+ one
+-two
++2
+ three
+ four
+ five
+@@ -8,3 +8,4 @@
+ eight
+ nine
+ ten
++eleven
+`, unifiedDiff(expected, actual))
+
+	require.Equal(t, "--- expected\n+++ actual\n@@ -0,0 +1 @@\n+added\n", unifiedDiff("", "added"))
+}
@@ -0,0 +1,184 @@
+package specform
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+)
+
+// ErrInputRejected is returned, wrapped, when an input matches a prompt
+// injection pattern and its spec declares the reject-injection policy.
+var ErrInputRejected = errors.New("input rejected")
+
+// injectionPatterns are common prompt injection phrases and chat template
+// markers rejected by the reject-injection policy.
+var injectionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\s+(all\s+|any\s+|the\s+)?(previous|prior|above|earlier|preceding)\s+(instructions|prompts|rules|directions|context)`),
+	regexp.MustCompile(`(?i)\byou\s+are\s+now\s+(a|an|in)\b`),
+	regexp.MustCompile(`(?i)\b(reveal|print|show|repeat)\s+(your|the)\s+(system\s+prompt|instructions)`),
+	regexp.MustCompile(`(?i)\bnew\s+instructions\s*:`),
+	regexp.MustCompile(`(?im)^\s*(system|assistant)\s*:`),
+	regexp.MustCompile(`<\|(im_start|im_end|system|endoftext)\|>`),
+	regexp.MustCompile(`\[/?INST\]|<</?SYS>>`),
+}
+
+// fencePattern matches markdown code fence markers.
+var fencePattern = regexp.MustCompile("```+|~~~+")
+
+// escapePlan holds the compiled escape policies of a single input.
+type escapePlan struct {
+	input     string
+	policies  []string
+	delimiter string
+	reject    []*regexp.Regexp
+}
+
+// newEscapePlans compiles the escape policies declared for each input,
+// ordered by input name.
+func newEscapePlans(options map[string]types.InputOptions) ([]*escapePlan, error) {
+	var plans []*escapePlan
+	for name, opt := range options {
+		if len(opt.RejectPatterns) > 0 && !slices.Contains(opt.Escape, types.EscapeRejectInjection) {
+			return nil, fmt.Errorf("reject_patterns for input %s need the %s escape policy", name, types.EscapeRejectInjection)
+		}
+		if len(opt.Escape) == 0 {
+			continue
+		}
+
+		plan := &escapePlan{input: name, policies: opt.Escape, delimiter: opt.Delimiter}
+		for _, pattern := range opt.RejectPatterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid reject pattern for input %s: %w", name, err)
+			}
+			plan.reject = append(plan.reject, re)
+		}
+		plans = append(plans, plan)
+	}
+	sort.Slice(plans, func(i, j int) bool { return plans[i].input < plans[j].input })
+	return plans, nil
+}
+
+// applyEscaping enforces the escape policies of every input in values,
+// except the delimiter wrapping added by applyDelimiters. Each change is
+// recorded on trace when it is set.
+func (p *PreparedPrompt) applyEscaping(values map[string]string, trace *types.RenderTrace) error {
+	for _, plan := range p.escapePlans {
+		name := plan.input
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+
+		record := func(policy, detail string) {
+			if trace != nil {
+				trace.Escapes = append(trace.Escapes, types.EscapeTrace{Input: name, Policy: policy, Detail: detail})
+			}
+		}
+
+		if plan.has(types.EscapeStripControl) {
+			var removed int
+			value, removed = stripControl(value)
+			if removed > 0 {
+				record(types.EscapeStripControl, fmt.Sprintf("removed %d control characters", removed))
+			}
+		}
+
+		if plan.has(types.EscapeFences) {
+			escaped := 0
+			value = fencePattern.ReplaceAllStringFunc(value, func(fence string) string {
+				escaped++
+				return `\` + strings.Join(strings.Split(fence, ""), `\`)
+			})
+			if escaped > 0 {
+				record(types.EscapeFences, fmt.Sprintf("escaped %d fence markers", escaped))
+			}
+		}
+
+		if plan.has(types.EscapeRejectInjection) {
+			for _, re := range append(injectionPatterns, plan.reject...) {
+				if match := re.FindString(value); match != "" {
+					return fmt.Errorf("%w: %s matches injection pattern %q", ErrInputRejected, name, match)
+				}
+			}
+		}
+
+		if plan.has(types.EscapeDelimit) {
+			_, close := plan.delimiters(name)
+			if escaped := strings.Count(value, close); escaped > 0 {
+				_, size := utf8.DecodeRuneInString(close)
+				value = strings.ReplaceAll(value, close, close[:size]+`\`+close[size:])
+				record(types.EscapeDelimit, fmt.Sprintf("escaped %d closing delimiters", escaped))
+			}
+		}
+
+		values[name] = value
+	}
+	return nil
+}
+
+// applyDelimiters wraps the inputs with the delimit policy in their
+// delimiters. It runs after the token budget, so truncation never cuts a
+// delimiter off; applyBudget reserves their tokens instead.
+func (p *PreparedPrompt) applyDelimiters(values map[string]string, trace *types.RenderTrace) {
+	for _, plan := range p.escapePlans {
+		value, ok := values[plan.input]
+		if !ok || !plan.has(types.EscapeDelimit) {
+			continue
+		}
+
+		open, close := plan.delimiters(plan.input)
+		values[plan.input] = open + "\n" + value + "\n" + close
+		if trace != nil {
+			trace.Escapes = append(trace.Escapes, types.EscapeTrace{Input: plan.input, Policy: types.EscapeDelimit, Detail: "wrapped in " + open})
+		}
+	}
+}
+
+// delimiterTokens returns the tokens applyDelimiters adds to an input.
+func (p *PreparedPrompt) delimiterTokens(name string, tok Tokenizer) int {
+	for _, plan := range p.escapePlans {
+		if plan.input == name && plan.has(types.EscapeDelimit) {
+			open, close := plan.delimiters(name)
+			return tok.Count(open+"\n") + tok.Count("\n"+close)
+		}
+	}
+	return 0
+}
+
+func (e *escapePlan) has(policy string) bool {
+	return slices.Contains(e.policies, policy)
+}
+
+// delimiters returns the opening and closing delimiter for an input. The
+// default is an XML style tag named after the input.
+func (e *escapePlan) delimiters(name string) (string, string) {
+	if e.delimiter != "" {
+		return e.delimiter, e.delimiter
+	}
+	return "<" + name + ">", "</" + name + ">"
+}
+
+// stripControl removes control and invisible formatting characters, such as
+// bidi overrides and zero width spaces, keeping tabs and newlines.
+func stripControl(s string) (string, int) {
+	removed := 0
+	out := strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' || r == '\r' {
+			return r
+		}
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			removed++
+			return -1
+		}
+		return r
+	}, s)
+	return out, removed
+}
@@ -0,0 +1,42 @@
+package specform
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/specform/specform/sdk/go/specform/internal"
+	"github.com/specform/specform/sdk/go/specform/types"
+)
+
+// The expr assertion evaluates a boolean expression against the output,
+// e.g. `len(words(output)) < 100 && contains(lower(output), inputs.product)`.
+// Expressions are type-checked when the spec is compiled and run with step
+// and size limits; see internal/expr.go for the language.
+
+var exprCache compileCache[*internal.Expr]
+
+// compileExpr returns the compiled expression for source, compiling it
+// once.
+func compileExpr(source string) (*internal.Expr, error) {
+	return exprCache.get(source, internal.CompileExpr)
+}
+
+func assertExpr(ctx context.Context, value, output string, actx *types.AssertionContext) types.AssertionResult {
+	e, err := compileExpr(value)
+	if err != nil {
+		return errorResult("expr", value, err.Error())
+	}
+
+	env := internal.ExprEnv{Output: output}
+	if actx != nil {
+		env.Reference, env.Inputs = actx.Reference, actx.Inputs
+	}
+
+	passed, err := e.Eval(ctx, env, internal.DefaultExprLimits)
+	if err != nil {
+		return errorResult("expr", value, fmt.Sprintf("Expression failed: %s", err))
+	}
+
+	msg := passFailMsg(passed, "Expression is true: %s", "Expression is false: %s", e.String())
+	return types.AssertionResult{Type: "expr", Value: value, Passed: passed, Message: msg, Expected: "true"}
+}
@@ -0,0 +1,33 @@
+package specform
+
+import (
+	"github.com/specform/specform/sdk/go/specform/internal"
+)
+
+// RegisterTemplateFunc registers a function that prompt templates can call,
+// for example {{slugify title}}. The function must return a single value, or a
+// value and an error. Functions must be registered before the specs that use
+// them are compiled or prepared; unknown functions are reported as errors when
+// a spec is compiled.
+//
+// Built-in functions:
+//   - upper, lower, trim: {{upper tone}}
+//   - truncate: {{truncate article 2000}} keeps the first 2000 characters
+//   - json: {{json article}} encodes the value as JSON
+//   - join: {{join tags ", "}} joins a JSON array or newline separated list
+//   - default: {{default tone "casual"}} falls back when the value is empty
+//   - indent: {{indent code 4}} indents every line by 4 spaces
+func RegisterTemplateFunc(name string, fn any) error {
+	return internal.DefaultFuncs.Register(name, fn)
+}
+
+// UnregisterTemplateFunc removes a template function. Prompts prepared
+// before the call keep the function.
+func UnregisterTemplateFunc(name string) error {
+	return internal.DefaultFuncs.Unregister(name)
+}
+
+// HasTemplateFunc reports whether a template function is registered.
+func HasTemplateFunc(name string) bool {
+	return internal.DefaultFuncs.Has(name)
+}
@@ -0,0 +1,72 @@
+package specform
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/specform/specform/sdk/go/specform/internal"
+	"github.com/specform/specform/sdk/go/specform/types"
+)
+
+// Assertion values can reference inputs like the prompt does, e.g.
+// `contains: {{customer_name}}` or `matches: /{{product}}/i`. Values are
+// rendered with AssertionContext.Inputs and the prompt's template functions
+// before the assertion runs. Each value is parsed once and cached.
+
+// AssertionInputs merges the caller's inputs over the prompt's default
+// values, the same way RenderPrompt does, for use as
+// AssertionContext.Inputs.
+func AssertionInputs(prompt *types.CompiledPrompt, inputs map[string]string) map[string]string {
+	merged := make(map[string]string, len(prompt.Values)+len(inputs))
+	for k, v := range prompt.Values {
+		merged[k] = v
+	}
+	for k, v := range inputs {
+		merged[k] = v
+	}
+	return merged
+}
+
+// valueTemplates caches the parsed templates of assertion values, keyed by
+// whether the value starts with a regex and the value itself.
+var valueTemplates compileCache[*template.Template]
+
+// interpolateValue renders the input references in an assertion value.
+// Values without a template action are returned unchanged, and referencing
+// an input that has no value is an error. Inputs inside the regex of a
+// regex assertion are quoted so they match literally.
+func interpolateValue(typ, value string, ctx *types.AssertionContext) (string, error) {
+	if !strings.Contains(value, "{{") {
+		return value, nil
+	}
+
+	typ = strings.TrimPrefix(typ, types.NegatePrefix)
+	key := "\x00" + value
+	if internal.IsRegexAssertion(typ) {
+		key = typ + key
+	}
+	tpl, err := valueTemplates.get(key, func(string) (*template.Template, error) {
+		if internal.IsRegexAssertion(typ) {
+			return internal.ParseRegexTemplate("assertion", typ, value, internal.DefaultFuncs)
+		}
+		return internal.ParseTemplate("assertion", value, internal.DefaultFuncs)
+	})
+	if err != nil {
+		return "", fmt.Errorf("invalid assertion value template: %w", err)
+	}
+
+	var inputs map[string]string
+	if ctx != nil {
+		inputs = ctx.Inputs
+	}
+	if inputs == nil {
+		inputs = map[string]string{}
+	}
+
+	var sb strings.Builder
+	if err := tpl.Execute(&sb, inputs); err != nil {
+		return "", fmt.Errorf("failed to interpolate assertion value: %w", err)
+	}
+	return sb.String(), nil
+}
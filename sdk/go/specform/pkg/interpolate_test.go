@@ -0,0 +1,68 @@
+package specform
+
+import (
+	"testing"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunAssertions_InterpolatesInputs(t *testing.T) {
+	prompt := &types.CompiledPrompt{Values: map[string]string{"product": "Specform", "customer": "Ada"}}
+	ctx := &types.AssertionContext{Inputs: AssertionInputs(prompt, map[string]string{"customer": "Grace"})}
+
+	results := RunAssertions("Hi Grace, thanks for trying Specform!", []types.Assertion{
+		{Type: "contains", Value: "{{customer}}"},
+		{Type: "matches", Value: "/thanks for trying {{upper product}}/i"},
+		{Type: "not-contains", Value: "Dear {{customer}}"},
+		{Type: "any-of", Assertions: []types.Assertion{{Type: "equals", Value: "{{product}}"}, {Type: "contains", Value: "{{product}}!"}}},
+	}, ctx)
+
+	for _, r := range results {
+		require.True(t, r.Passed, r.Message)
+	}
+	require.Equal(t, "Grace", results[0].Value)
+	require.Equal(t, "Specform!", results[3].Results[1].Value)
+}
+
+func TestRunAssertions_InterpolationErrors(t *testing.T) {
+	ctx := &types.AssertionContext{Inputs: map[string]string{"name": "{{secret}}"}}
+
+	results := RunAssertions("{{secret}}", []types.Assertion{
+		{Type: "contains", Value: "{{missing}}"},
+		{Type: "not-contains", Value: "{{missing}}"},
+		{Type: "contains", Value: "{{name}}"},
+	}, ctx)
+
+	require.False(t, results[0].Passed)
+	require.Contains(t, results[0].Error, "failed to interpolate assertion value")
+	require.Equal(t, "{{missing}}", results[0].Value)
+	require.False(t, results[1].Passed)
+
+	// Input values are not rendered again
+	require.True(t, results[2].Passed)
+	require.Equal(t, "{{secret}}", results[2].Value)
+}
+
+func TestRunAssertions_QuotesInputsInRegexes(t *testing.T) {
+	ctx := &types.AssertionContext{Inputs: map[string]string{"product": "C++", "version": "1.2", "id": "A.7"}}
+
+	results := RunAssertions("Order A.7: C++ 1.2 shipped", []types.Assertion{
+		{Type: "matches", Value: "/{{lower product}} {{version}}/i"},
+		{Type: "not-matches", Value: "/C {{version}}/"},
+		{Type: "match-count", Value: "/{{version}}/ == 1"},
+		{Type: "capture-equals", Value: `/Order (?P<id>\S+): {{product}}/ id {{id}}`},
+		{Type: "contains", Value: "{{product}} {{version}}"},
+	}, ctx)
+
+	for _, r := range results {
+		require.True(t, r.Passed, r.Message)
+	}
+	require.Equal(t, `/c\+\+ 1\.2/i`, results[0].Value)
+	require.Equal(t, `/Order (?P<id>\S+): C\+\+/ id A.7`, results[3].Value)
+	require.Equal(t, "C++ 1.2", results[4].Value)
+
+	// "1.2" matches only itself, not "1x2"
+	results = RunAssertions("C++ 1x2", []types.Assertion{{Type: "matches", Value: "/{{version}}/"}}, ctx)
+	require.False(t, results[0].Passed)
+}
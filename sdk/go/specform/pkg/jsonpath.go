@@ -0,0 +1,408 @@
+package specform
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+)
+
+// JSON path assertions take a value of the form "<path> <expected>", e.g.
+// "$.sentiment positive", "$.items >= 3" or "$.tags[0] /^urgent$/i". Paths
+// support $, .key, ['key'], [n] (negative from the end) and * wildcards;
+// a wildcard yields an array of every match.
+
+type pathStepKind int
+
+const (
+	stepKey pathStepKind = iota
+	stepIndex
+	stepWildcard
+)
+
+type pathStep struct {
+	kind  pathStepKind
+	key   string
+	index int
+}
+
+// parseJSONPath parses a JSONPath expression into steps.
+func parseJSONPath(path string) ([]pathStep, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("invalid JSON path %q: must start with $", path)
+	}
+
+	var steps []pathStep
+	rest := path[1:]
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			return nil, fmt.Errorf("invalid JSON path %q: recursive descent is not supported", path)
+
+		case rest[0] == '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			rest = rest[end:]
+			switch key {
+			case "":
+				return nil, fmt.Errorf("invalid JSON path %q: empty key", path)
+			case "*":
+				steps = append(steps, pathStep{kind: stepWildcard})
+			default:
+				steps = append(steps, pathStep{kind: stepKey, key: key})
+			}
+
+		case rest[0] == '[':
+			end := closingBracket(rest)
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSON path %q: unclosed [", path)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+
+			switch {
+			case inner == "*":
+				steps = append(steps, pathStep{kind: stepWildcard})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, pathStep{kind: stepKey, key: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid JSON path %q: bad index [%s]", path, inner)
+				}
+				steps = append(steps, pathStep{kind: stepIndex, index: index})
+			}
+
+		default:
+			return nil, fmt.Errorf("invalid JSON path %q: unexpected %q", path, rest[:1])
+		}
+	}
+	return steps, nil
+}
+
+// closingBracket returns the index of the ] closing the [ at the start of s,
+// skipping brackets inside quoted keys.
+func closingBracket(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '\'' || s[i] == '"':
+			quote = s[i]
+		case s[i] == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// evalJSONPath returns the value at the path. A path containing a wildcard
+// returns an array of every match.
+func evalJSONPath(doc any, steps []pathStep) (any, bool) {
+	nodes := []any{doc}
+	multi := false
+
+	for _, step := range steps {
+		var next []any
+		for _, node := range nodes {
+			switch step.kind {
+			case stepKey:
+				if obj, ok := node.(map[string]any); ok {
+					if v, ok := obj[step.key]; ok {
+						next = append(next, v)
+					}
+				}
+			case stepIndex:
+				if arr, ok := node.([]any); ok {
+					i := step.index
+					if i < 0 {
+						i += len(arr)
+					}
+					if i >= 0 && i < len(arr) {
+						next = append(next, arr[i])
+					}
+				}
+			case stepWildcard:
+				multi = true
+				switch v := node.(type) {
+				case []any:
+					next = append(next, v...)
+				case map[string]any:
+					keys := make([]string, 0, len(v))
+					for k := range v {
+						keys = append(keys, k)
+					}
+					sort.Strings(keys)
+					for _, k := range keys {
+						next = append(next, v[k])
+					}
+				}
+			}
+		}
+		nodes = next
+	}
+
+	if multi {
+		if nodes == nil {
+			nodes = []any{}
+		}
+		return nodes, true
+	}
+	if len(nodes) == 0 {
+		return nil, false
+	}
+	return nodes[0], true
+}
+
+// splitPathValue splits an assertion value into the JSON path and the rest.
+// The path ends at the first space outside brackets.
+func splitPathValue(value string) (string, string) {
+	value = strings.TrimSpace(value)
+	depth := 0
+	var quote byte
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case depth > 0 && (c == '\'' || c == '"'):
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == ' ' || c == '\t':
+			if depth == 0 {
+				return value[:i], strings.TrimSpace(value[i+1:])
+			}
+		}
+	}
+	return value, ""
+}
+
+// parseJSONOutput decodes the output as JSON. An output that is a single
+// fenced code block is unwrapped first.
+func parseJSONOutput(output string) (any, error) {
+	text := strings.TrimSpace(output)
+	if strings.HasPrefix(text, "```") && strings.HasSuffix(text, "```") && len(text) > 6 {
+		text = strings.TrimSuffix(text, "```")
+		if nl := strings.IndexByte(text, '\n'); nl >= 0 {
+			text = text[nl+1:]
+		} else {
+			text = strings.TrimPrefix(text, "```")
+		}
+	}
+
+	var doc any
+	if err := json.Unmarshal([]byte(text), &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// lookupJSONPath decodes the output and evaluates the path at the start of
+// value. On failure it returns a result describing the problem.
+func lookupJSONPath(typ, value, output string) (found any, rest string, fail *types.AssertionResult) {
+	path, rest := splitPathValue(value)
+	failWith := func(msg string) *types.AssertionResult {
+		return &types.AssertionResult{Type: typ, Value: value, Passed: false, Message: "✘ " + msg}
+	}
+
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		result := errorResult(typ, value, err.Error())
+		return nil, "", &result
+	}
+
+	doc, err := parseJSONOutput(output)
+	if err != nil {
+		return nil, "", failWith(fmt.Sprintf("Output is not valid JSON: %s", err))
+	}
+
+	found, ok := evalJSONPath(doc, steps)
+	if !ok {
+		return nil, "", failWith(fmt.Sprintf("%s not found in output", path))
+	}
+	return found, rest, nil
+}
+
+// parseJSONExpected decodes an expected value as JSON, falling back to the
+// raw string for values such as positive or 007.
+func parseJSONExpected(s string) any {
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return s
+	}
+	return v
+}
+
+// jsonValueEquals reports whether found matches the expected text, either
+// as decoded JSON or as a plain string.
+func jsonValueEquals(found any, expected string) bool {
+	if reflect.DeepEqual(found, parseJSONExpected(expected)) {
+		return true
+	}
+	s, ok := found.(string)
+	return ok && s == expected
+}
+
+// formatJSONValue renders a found value for a failure message.
+func formatJSONValue(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	s := string(data)
+	if utf8.RuneCountInString(s) > 80 {
+		s = string([]rune(s)[:80]) + "…"
+	}
+	return s
+}
+
+// jsonText returns a string value as is and anything else as JSON.
+func jsonText(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func assertIsJSON(value, output string, _ *types.AssertionContext) types.AssertionResult {
+	doc, err := parseJSONOutput(output)
+	if err != nil {
+		return types.AssertionResult{Type: "is-json", Value: value, Passed: false, Message: fmt.Sprintf("✘ Output is not valid JSON: %s", err)}
+	}
+
+	want := strings.ToLower(strings.TrimSpace(value))
+	got := jsonKind(doc)
+	if want != "" && want != got {
+		return types.AssertionResult{Type: "is-json", Value: value, Passed: false, Message: fmt.Sprintf("✘ Output is a JSON %s, expected %s", got, want), Expected: want, Actual: got}
+	}
+	return types.AssertionResult{Type: "is-json", Value: value, Passed: true, Message: "✔ Output is valid JSON", Expected: want, Actual: got}
+}
+
+func assertJSONPathEquals(value, output string, _ *types.AssertionContext) types.AssertionResult {
+	found, expected, fail := lookupJSONPath("json-path-equals", value, output)
+	if fail != nil {
+		return *fail
+	}
+
+	path, _ := splitPathValue(value)
+	passed := jsonValueEquals(found, expected)
+	msg := fmt.Sprintf("✔ %s equals %s", path, expected)
+	if !passed {
+		msg = fmt.Sprintf("✘ %s is %s, expected %s", path, formatJSONValue(found), expected)
+	}
+	return types.AssertionResult{Type: "json-path-equals", Value: value, Passed: passed, Message: msg, Expected: expected, Actual: jsonText(found)}
+}
+
+func assertJSONPathContains(value, output string, _ *types.AssertionContext) types.AssertionResult {
+	found, needle, fail := lookupJSONPath("json-path-contains", value, output)
+	if fail != nil {
+		return *fail
+	}
+
+	passed := false
+	switch v := found.(type) {
+	case []any:
+		for _, item := range v {
+			if jsonValueEquals(item, needle) {
+				passed = true
+				break
+			}
+		}
+	case map[string]any:
+		_, passed = v[needle]
+	default:
+		passed = strings.Contains(normalizeText(jsonText(v)), normalizeText(needle))
+	}
+
+	path, _ := splitPathValue(value)
+	msg := fmt.Sprintf("✔ %s contains '%s'", path, needle)
+	if !passed {
+		msg = fmt.Sprintf("✘ %s is %s, missing '%s'", path, formatJSONValue(found), needle)
+	}
+	return types.AssertionResult{Type: "json-path-contains", Value: value, Passed: passed, Message: msg, Expected: needle, Actual: jsonText(found)}
+}
+
+func assertJSONPathMatches(value, output string, _ *types.AssertionContext) types.AssertionResult {
+	found, rest, fail := lookupJSONPath("json-path-matches", value, output)
+	if fail != nil {
+		return *fail
+	}
+
+	re, err := compileRegex(rest)
+	if err != nil {
+		return errorResult("json-path-matches", value, fmt.Sprintf("Invalid regex: %s", err))
+	}
+
+	path, _ := splitPathValue(value)
+	passed := re.MatchString(jsonText(found))
+	msg := fmt.Sprintf("✔ %s matches regex %s", path, rest)
+	if !passed {
+		msg = fmt.Sprintf("✘ %s is %s, does not match regex %s", path, formatJSONValue(found), rest)
+	}
+	return types.AssertionResult{Type: "json-path-matches", Value: value, Passed: passed, Message: msg, Expected: rest, Actual: jsonText(found)}
+}
+
+func assertJSONPathLength(value, output string, _ *types.AssertionContext) types.AssertionResult {
+	found, rest, fail := lookupJSONPath("json-path-length", value, output)
+	if fail != nil {
+		return *fail
+	}
+
+	cmp, err := parseComparison(rest)
+	if err != nil {
+		return errorResult("json-path-length", value, err.Error())
+	}
+
+	path, _ := splitPathValue(value)
+	var length int
+	switch v := found.(type) {
+	case []any:
+		length = len(v)
+	case map[string]any:
+		length = len(v)
+	case string:
+		length = utf8.RuneCountInString(v)
+	default:
+		return types.AssertionResult{Type: "json-path-length", Value: value, Passed: false, Message: fmt.Sprintf("✘ %s is a JSON %s and has no length", path, jsonKind(v))}
+	}
+
+	measured := float64(length)
+	passed := cmp.test(measured)
+	msg := fmt.Sprintf("%s %s has length %d (expected %s)", boolPrefix(passed), path, length, cmp)
+	return types.AssertionResult{Type: "json-path-length", Value: value, Passed: passed, Message: msg, Expected: cmp.String(), Actual: formatNumber(measured), Measured: &measured}
+}
+
+func jsonKind(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
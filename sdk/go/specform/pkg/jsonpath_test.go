@@ -0,0 +1,66 @@
+package specform
+
+import (
+	"testing"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONPathAssertions(t *testing.T) {
+	output := "```json\n" + `{
+  "sentiment": "positive",
+  "summary": "Webhooks push events in real time.",
+  "items": [{"name": "retry", "count": 3}, {"name": "signing", "count": 1}],
+  "tags": ["urgent", "billing"],
+  "meta": {"score": 0.9, "reviewed": true, "my key": "x"}
+}` + "\n```"
+
+	tests := []struct {
+		name     string
+		typ      string
+		value    string
+		passed   bool
+		contains string
+	}{
+		{"is-json", "is-json", "", true, "valid JSON"},
+		{"is-json object", "is-json", "object", true, ""},
+		{"is-json wrong kind", "is-json", "array", false, "expected array"},
+		{"equals string", "json-path-equals", "$.sentiment positive", true, ""},
+		{"equals quoted string", "json-path-equals", `$.sentiment "positive"`, true, ""},
+		{"equals mismatch", "json-path-equals", "$.sentiment negative", false, `$.sentiment is "positive", expected negative`},
+		{"equals number", "json-path-equals", "$.items[0].count 3", true, ""},
+		{"equals negative index", "json-path-equals", "$.items[-1].name signing", true, ""},
+		{"equals bool", "json-path-equals", "$.meta.reviewed true", true, ""},
+		{"equals bracket key", "json-path-equals", "$.meta['my key'] x", true, ""},
+		{"equals wildcard", "json-path-equals", `$.items[*].name ["retry","signing"]`, true, ""},
+		{"missing path", "json-path-equals", "$.missing x", false, "$.missing not found"},
+		{"contains text", "json-path-contains", "$.summary webhook", true, ""},
+		{"contains array", "json-path-contains", "$.tags billing", true, ""},
+		{"contains array miss", "json-path-contains", "$.tags sales", false, `$.tags is ["urgent","billing"], missing 'sales'`},
+		{"contains key", "json-path-contains", "$.meta score", true, ""},
+		{"matches", "json-path-matches", "$.tags[0] /^URGENT$/i", true, ""},
+		{"matches miss", "json-path-matches", "$.sentiment /^neg/", false, "does not match"},
+		{"length array", "json-path-length", "$.items >= 2", true, ""},
+		{"length fail", "json-path-length", "$.items ≥ 3", false, "has length 2 (expected >= 3)"},
+		{"length string", "json-path-length", "$.sentiment 8", true, ""},
+		{"length of number", "json-path-length", "$.meta.score 1", false, "has no length"},
+		{"invalid path", "json-path-equals", "sentiment positive", false, "must start with $"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := RunAssertion(output, types.Assertion{Type: tt.typ, Value: tt.value}, nil)
+			require.NoError(t, err)
+			require.Equal(t, tt.passed, res.Passed, res.Message)
+			require.Contains(t, res.Message, tt.contains)
+		})
+	}
+}
+
+func TestJSONPathAssertions_InvalidOutput(t *testing.T) {
+	res, err := RunAssertion("Sure! Here is the JSON", types.Assertion{Type: "json-path-equals", Value: "$.a 1"}, nil)
+	require.NoError(t, err)
+	require.False(t, res.Passed)
+	require.Contains(t, res.Message, "Output is not valid JSON")
+}
@@ -0,0 +1,230 @@
+package specform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+)
+
+// JudgeProvider grades outputs for the judge assertion. Set it on
+// AssertionContext.
+type JudgeProvider = types.JudgeProvider
+
+// assertJudge sends the rubric in the assertion value, the output and the
+// inputs to the context's judge and passes when the verdict does. Secret
+// inputs are left out, and copies of their values in the output are
+// replaced with placeholders, since the judge is usually a remote model.
+func assertJudge(ctx context.Context, value, output string, actx *types.AssertionContext) types.AssertionResult {
+	if actx == nil || actx.Judge == nil {
+		return errorResult("judge", value, "No judge provider configured")
+	}
+
+	inputs := actx.Inputs
+	if len(actx.Secrets) > 0 {
+		inputs = make(map[string]string, len(actx.Inputs))
+		for k, v := range actx.Inputs {
+			if _, secret := actx.Secrets[k]; !secret {
+				inputs[k] = v
+			}
+		}
+		output = SecretsOnlyRedactor.Redact(output, actx.Secrets)
+	}
+
+	verdict, err := actx.Judge.Judge(ctx, types.JudgeRequest{
+		Rubric: value,
+		Output: output,
+		Inputs: inputs,
+	})
+	if err != nil {
+		return errorResult("judge", value, fmt.Sprintf("Judge failed: %s", err))
+	}
+
+	score := verdict.Score
+	msg := fmt.Sprintf("%s Judge score %.2f: %s", boolPrefix(verdict.Pass), score, verdict.Reason)
+	return types.AssertionResult{Type: "judge", Value: value, Passed: verdict.Pass, Message: msg, Actual: verdict.Reason, Score: &score}
+}
+
+// MockJudge is a deterministic JudgeProvider for tests. It returns the
+// verdict registered for a rubric, or Default, and records every request.
+type MockJudge struct {
+	Verdicts map[string]types.JudgeVerdict // rubric → verdict
+	Default  types.JudgeVerdict
+
+	mu       sync.Mutex
+	requests []types.JudgeRequest
+}
+
+// Judge returns the verdict for the request's rubric.
+func (m *MockJudge) Judge(_ context.Context, req types.JudgeRequest) (types.JudgeVerdict, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests = append(m.requests, req)
+	if v, ok := m.Verdicts[req.Rubric]; ok {
+		return v, nil
+	}
+	return m.Default, nil
+}
+
+// Requests returns the requests the mock has received.
+func (m *MockJudge) Requests() []types.JudgeRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]types.JudgeRequest(nil), m.requests...)
+}
+
+// OpenAIJudge is a JudgeProvider for OpenAI compatible chat completion
+// APIs. It asks the model for a JSON verdict.
+type OpenAIJudge struct {
+	BaseURL string // e.g. https://api.openai.com/v1
+	APIKey  string
+	Model   string
+	Client  *http.Client
+}
+
+// NewOpenAIJudge returns an OpenAIJudge with a 60 second request timeout.
+func NewOpenAIJudge(baseURL, apiKey, model string) *OpenAIJudge {
+	return &OpenAIJudge{
+		BaseURL: baseURL,
+		APIKey:  apiKey,
+		Model:   model,
+		Client:  &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+const judgeSystemPrompt = `You are a strict evaluator. Grade the output against the rubric, using the inputs as context.
+Reply with only a JSON object: {"pass": boolean, "score": number between 0 and 1, "reason": "one sentence"}.`
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model          string            `json:"model"`
+	Messages       []chatMessage     `json:"messages"`
+	Temperature    float64           `json:"temperature"`
+	ResponseFormat map[string]string `json:"response_format,omitempty"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// Judge sends the request to the chat completions endpoint and parses the
+// verdict from the reply.
+func (j *OpenAIJudge) Judge(ctx context.Context, req types.JudgeRequest) (types.JudgeVerdict, error) {
+	body, err := json.Marshal(chatRequest{
+		Model: j.Model,
+		Messages: []chatMessage{
+			{Role: "system", Content: judgeSystemPrompt},
+			{Role: "user", Content: judgeUserPrompt(req)},
+		},
+		ResponseFormat: map[string]string{"type": "json_object"},
+	})
+	if err != nil {
+		return types.JudgeVerdict{}, fmt.Errorf("failed to encode judge request: %w", err)
+	}
+
+	url := strings.TrimSuffix(j.BaseURL, "/") + "/chat/completions"
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return types.JudgeVerdict{}, fmt.Errorf("failed to create judge request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if j.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+j.APIKey)
+	}
+
+	client := j.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return types.JudgeVerdict{}, fmt.Errorf("judge request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return types.JudgeVerdict{}, fmt.Errorf("failed to read judge response: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		return types.JudgeVerdict{}, fmt.Errorf("judge request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var chat chatResponse
+	if err := json.Unmarshal(data, &chat); err != nil {
+		return types.JudgeVerdict{}, fmt.Errorf("failed to decode judge response: %w", err)
+	}
+	if len(chat.Choices) == 0 {
+		return types.JudgeVerdict{}, fmt.Errorf("judge response has no choices")
+	}
+	return parseJudgeVerdict(chat.Choices[0].Message.Content)
+}
+
+// judgeUserPrompt formats the rubric, inputs and output for the judge.
+func judgeUserPrompt(req types.JudgeRequest) string {
+	var sb strings.Builder
+	sb.WriteString("Rubric:\n")
+	sb.WriteString(req.Rubric)
+
+	if len(req.Inputs) > 0 {
+		names := make([]string, 0, len(req.Inputs))
+		for name := range req.Inputs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		sb.WriteString("\n\nInputs:\n")
+		for _, name := range names {
+			fmt.Fprintf(&sb, "<%s>\n%s\n</%s>\n", name, req.Inputs[name], name)
+		}
+	}
+
+	sb.WriteString("\n\nOutput:\n<output>\n")
+	sb.WriteString(req.Output)
+	sb.WriteString("\n</output>")
+	return sb.String()
+}
+
+// parseJudgeVerdict decodes the JSON verdict in a judge reply. The score is
+// clamped to [0, 1].
+func parseJudgeVerdict(content string) (types.JudgeVerdict, error) {
+	doc, err := parseJSONOutput(content)
+	if err != nil {
+		return types.JudgeVerdict{}, fmt.Errorf("invalid judge verdict: %w", err)
+	}
+	obj, ok := doc.(map[string]any)
+	if !ok {
+		return types.JudgeVerdict{}, fmt.Errorf("invalid judge verdict: expected a JSON object")
+	}
+	pass, ok := obj["pass"].(bool)
+	if !ok {
+		return types.JudgeVerdict{}, fmt.Errorf("invalid judge verdict: missing pass")
+	}
+
+	verdict := types.JudgeVerdict{Pass: pass}
+	if score, ok := obj["score"].(float64); ok {
+		verdict.Score = min(max(score, 0), 1)
+	} else if pass {
+		verdict.Score = 1
+	}
+	if reason, ok := obj["reason"].(string); ok {
+		verdict.Reason = reason
+	}
+	return verdict, nil
+}
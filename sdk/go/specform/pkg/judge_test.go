@@ -0,0 +1,117 @@
+package specform
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJudgeAssertion_Mock(t *testing.T) {
+	judge := &MockJudge{
+		Verdicts: map[string]types.JudgeVerdict{
+			"Is the tone casual?": {Pass: true, Score: 0.9, Reason: "Friendly wording"},
+		},
+		Default: types.JudgeVerdict{Pass: false, Score: 0.2, Reason: "Rubric not met"},
+	}
+	ctx := &types.AssertionContext{Judge: judge, Inputs: map[string]string{"tone": "casual"}}
+
+	results := RunAssertions("Hey! Webhooks are neat.", []types.Assertion{
+		{Type: "judge", Value: "Is the tone casual?"},
+		{Type: "judge", Value: "Does it avoid medical advice?"},
+	}, ctx)
+
+	require.True(t, results[0].Passed, results[0].Message)
+	require.Equal(t, "✔ Judge score 0.90: Friendly wording", results[0].Message)
+	require.Equal(t, 0.9, *results[0].Score)
+	require.False(t, results[1].Passed)
+
+	requests := judge.Requests()
+	require.Len(t, requests, 2)
+	require.Equal(t, "Hey! Webhooks are neat.", requests[0].Output)
+	require.Equal(t, "casual", requests[0].Inputs["tone"])
+}
+
+func TestJudgeAssertion_LeavesOutSecrets(t *testing.T) {
+	judge := &MockJudge{Default: types.JudgeVerdict{Pass: true, Score: 1}}
+	prompt := &types.CompiledPrompt{
+		InputOptions: map[string]types.InputOptions{"api_key": {Secret: true}},
+	}
+	inputs := map[string]string{"tone": "casual", "api_key": "sk-hunter2"}
+	ctx := &types.AssertionContext{
+		Judge:   judge,
+		Inputs:  AssertionInputs(prompt, inputs),
+		Secrets: SecretInputs(prompt, inputs),
+	}
+
+	results := RunAssertions("Use sk-hunter2 to call the API", []types.Assertion{{Type: "judge", Value: "Is it helpful?"}}, ctx)
+	require.True(t, results[0].Passed, results[0].Message)
+
+	req := judge.Requests()[0]
+	require.Equal(t, map[string]string{"tone": "casual"}, req.Inputs)
+	require.NotContains(t, req.Output, "sk-hunter2")
+	require.Regexp(t, `^Use \[REDACTED:api_key:[0-9a-f]{8}\] to call the API$`, req.Output)
+}
+
+func TestJudgeAssertion_NoProvider(t *testing.T) {
+	res, err := RunAssertion("output", types.Assertion{Type: "judge", Value: "Is it polite?"}, nil)
+	require.NoError(t, err)
+	require.False(t, res.Passed)
+	require.Contains(t, res.Message, "No judge provider configured")
+}
+
+func TestOpenAIJudge(t *testing.T) {
+	var got chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/chat/completions", r.URL.Path)
+		require.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{
+				"message": map[string]string{
+					"role":    "assistant",
+					"content": "```json\n{\"pass\": true, \"score\": 1.4, \"reason\": \"Casual and short\"}\n```",
+				},
+			}},
+		})
+	}))
+	defer server.Close()
+
+	judge := NewOpenAIJudge(server.URL+"/v1/", "test-key", "gpt-4o-mini")
+	res, err := RunAssertion("Hey there!", types.Assertion{Type: "judge", Value: "Is the tone casual?"}, &types.AssertionContext{
+		Judge:  judge,
+		Inputs: map[string]string{"tone": "casual"},
+	})
+	require.NoError(t, err)
+	require.True(t, res.Passed, res.Message)
+	require.Equal(t, 1.0, *res.Score)
+
+	require.Equal(t, "gpt-4o-mini", got.Model)
+	require.Len(t, got.Messages, 2)
+	require.Contains(t, got.Messages[1].Content, "Is the tone casual?")
+	require.Contains(t, got.Messages[1].Content, "<tone>\ncasual\n</tone>")
+	require.Contains(t, got.Messages[1].Content, "<output>\nHey there!\n</output>")
+}
+
+func TestOpenAIJudge_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": "rate limited"}`, http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	res, err := RunAssertion("output", types.Assertion{Type: "judge", Value: "Is it polite?"}, &types.AssertionContext{
+		Judge: NewOpenAIJudge(server.URL, "", "gpt-4o-mini"),
+	})
+	require.NoError(t, err)
+	require.False(t, res.Passed)
+	require.Contains(t, res.Message, "status 429")
+
+	_, err = parseJudgeVerdict(`{"score": 0.5}`)
+	require.ErrorContains(t, err, "missing pass")
+	_, err = parseJudgeVerdict(`I think it passes`)
+	require.ErrorContains(t, err, "invalid judge verdict")
+}
@@ -0,0 +1,203 @@
+package specform
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/specform/specform/sdk/go/specform/internal"
+	"github.com/specform/specform/sdk/go/specform/types"
+)
+
+// Lexical similarity assertions compare the output with the reference in
+// AssertionContext.Reference, which the CLI fills from the spec's output
+// fence. The assertion value is the threshold: "0.7" means ">= 0.7", other
+// operators can be given explicitly and an empty value uses the default.
+
+// lexicalMetric scores a candidate against a reference between 0 and 1.
+type lexicalMetric struct {
+	label     string
+	threshold float64 // default minimum score
+	score     func(candidate, reference string) float64
+}
+
+var lexicalMetrics = map[string]lexicalMetric{
+	"levenshtein": {"Levenshtein ratio", 0.8, levenshteinRatio},
+	"rouge-1":     {"ROUGE-1", 0.5, rouge1},
+	"rouge-l":     {"ROUGE-L", 0.4, rougeL},
+	"bleu":        {"BLEU", 0.3, bleu},
+	"jaccard":     {"Jaccard", 0.5, jaccard},
+}
+
+// lexicalAssertion builds the assertion for a lexical metric.
+func lexicalAssertion(name string) AssertionFn {
+	metric := lexicalMetrics[name]
+	return func(value, output string, ctx *types.AssertionContext) types.AssertionResult {
+		if ctx == nil || strings.TrimSpace(ctx.Reference) == "" {
+			return errorResult(name, value, "No reference output to compare with, add an output fence to the spec")
+		}
+
+		cmp, err := parseThreshold(value, metric.threshold)
+		if err != nil {
+			return errorResult(name, value, err.Error())
+		}
+
+		score := metric.score(output, ctx.Reference)
+		passed := cmp.test(score)
+		msg := fmt.Sprintf("%s %s %.2f vs threshold %s", boolPrefix(passed), metric.label, score, cmp)
+		return types.AssertionResult{Type: name, Value: value, Passed: passed, Message: msg, Expected: cmp.String(), Score: &score}
+	}
+}
+
+// parseThreshold parses a score threshold. A bare number is a minimum.
+func parseThreshold(value string, def float64) (comparison, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return comparison{op: ">=", n: def}, nil
+	}
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		return comparison{op: ">=", n: n}, nil
+	}
+	return parseComparison(value)
+}
+
+// lexicalTokens returns the lower-cased words of s.
+func lexicalTokens(s string) []string {
+	return internal.Words(strings.ToLower(s))
+}
+
+// levenshteinRatio returns 1 minus the edit distance between the normalized
+// texts divided by the length of the longer one.
+func levenshteinRatio(candidate, reference string) float64 {
+	a := []rune(strings.Join(lexicalTokens(candidate), " "))
+	b := []rune(strings.Join(lexicalTokens(reference), " "))
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return 1
+	}
+
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return 1 - float64(prev[len(b)])/float64(longest)
+}
+
+// rouge1 returns the F1 score of the unigram overlap.
+func rouge1(candidate, reference string) float64 {
+	cand, ref := lexicalTokens(candidate), lexicalTokens(reference)
+	return f1(clippedOverlap(ngrams(cand, 1), ngrams(ref, 1)), len(cand), len(ref))
+}
+
+// rougeL returns the F1 score of the longest common token subsequence.
+func rougeL(candidate, reference string) float64 {
+	cand, ref := lexicalTokens(candidate), lexicalTokens(reference)
+
+	prev := make([]int, len(ref)+1)
+	curr := make([]int, len(ref)+1)
+	for i := 1; i <= len(cand); i++ {
+		for j := 1; j <= len(ref); j++ {
+			if cand[i-1] == ref[j-1] {
+				curr[j] = prev[j-1] + 1
+			} else {
+				curr[j] = max(prev[j], curr[j-1])
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return f1(prev[len(ref)], len(cand), len(ref))
+}
+
+// bleu returns the BLEU score with n-grams up to 4, add-one smoothing for
+// n > 1 and the brevity penalty.
+func bleu(candidate, reference string) float64 {
+	cand, ref := lexicalTokens(candidate), lexicalTokens(reference)
+	if len(cand) == 0 || len(ref) == 0 {
+		return 0
+	}
+
+	maxN := min(4, len(cand))
+	logSum := 0.0
+	for n := 1; n <= maxN; n++ {
+		overlap := float64(clippedOverlap(ngrams(cand, n), ngrams(ref, n)))
+		total := float64(len(cand) - n + 1)
+		if n > 1 {
+			overlap++
+			total++
+		}
+		if overlap == 0 {
+			return 0
+		}
+		logSum += math.Log(overlap / total)
+	}
+
+	penalty := 1.0
+	if len(cand) < len(ref) {
+		penalty = math.Exp(1 - float64(len(ref))/float64(len(cand)))
+	}
+	return penalty * math.Exp(logSum/float64(maxN))
+}
+
+// jaccard returns the size of the intersection of the token sets divided by
+// the size of their union.
+func jaccard(candidate, reference string) float64 {
+	a, b := map[string]bool{}, map[string]bool{}
+	for _, t := range lexicalTokens(candidate) {
+		a[t] = true
+	}
+	for _, t := range lexicalTokens(reference) {
+		b[t] = true
+	}
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+
+	shared := 0
+	for t := range a {
+		if b[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// ngrams counts the n-grams of tokens.
+func ngrams(tokens []string, n int) map[string]int {
+	counts := map[string]int{}
+	for i := 0; i+n <= len(tokens); i++ {
+		counts[strings.Join(tokens[i:i+n], " ")]++
+	}
+	return counts
+}
+
+// clippedOverlap counts the candidate n-grams found in the reference, each
+// at most as often as it occurs there.
+func clippedOverlap(cand, ref map[string]int) int {
+	overlap := 0
+	for gram, count := range cand {
+		overlap += min(count, ref[gram])
+	}
+	return overlap
+}
+
+func f1(overlap, candLen, refLen int) float64 {
+	if overlap == 0 || candLen == 0 || refLen == 0 {
+		return 0
+	}
+	precision := float64(overlap) / float64(candLen)
+	recall := float64(overlap) / float64(refLen)
+	return 2 * precision * recall / (precision + recall)
+}
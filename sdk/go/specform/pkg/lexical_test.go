@@ -0,0 +1,64 @@
+package specform
+
+import (
+	"testing"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLexicalScores(t *testing.T) {
+	ref := "the cat sat on the mat"
+
+	require.Equal(t, 1.0, levenshteinRatio("The cat sat on the mat.", ref))
+	require.InDelta(t, 1-1.0/22, levenshteinRatio("the cat sat on the hat", ref), 1e-9)
+	require.Equal(t, 1.0, levenshteinRatio("", ""))
+
+	require.InDelta(t, 5.0/6, rouge1("the cat sat on the hat", ref), 1e-9)
+	require.InDelta(t, 2*(4.0/4)*(4.0/6)/(1+4.0/6), rougeL("the cat on mat", ref), 1e-9)
+
+	require.InDelta(t, 1.0, bleu(ref, ref), 1e-9)
+	require.Less(t, bleu("the cat", ref), 0.5)
+	require.Zero(t, bleu("dogs bark loudly", ref))
+
+	require.InDelta(t, 4.0/7, jaccard("the cat sat on a rug", ref), 1e-9)
+}
+
+func TestLexicalAssertions(t *testing.T) {
+	ctx := &types.AssertionContext{Reference: "Webhooks send real-time HTTP requests between systems."}
+	output := "Webhooks send HTTP requests between systems in real time."
+
+	tests := []struct {
+		typ    string
+		value  string
+		passed bool
+	}{
+		{"levenshtein", "0.5", true},
+		{"levenshtein", "0.99", false},
+		{"rouge-1", "", true},
+		{"rouge-l", ">= 0.6", true},
+		{"bleu", "", true},
+		{"bleu", "0.9", false},
+		{"jaccard", "> 0.5", true},
+		{"jaccard", "< 0.5", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typ+" "+tt.value, func(t *testing.T) {
+			res, err := RunAssertion(output, types.Assertion{Type: tt.typ, Value: tt.value}, ctx)
+			require.NoError(t, err)
+			require.Equal(t, tt.passed, res.Passed, res.Message)
+			require.NotNil(t, res.Score)
+		})
+	}
+
+	res, err := RunAssertion(output, types.Assertion{Type: "rouge-l", Value: "0.5"}, nil)
+	require.NoError(t, err)
+	require.False(t, res.Passed)
+	require.Contains(t, res.Message, "No reference output")
+
+	res, err = RunAssertion(output, types.Assertion{Type: "bleu", Value: "high"}, ctx)
+	require.NoError(t, err)
+	require.False(t, res.Passed)
+	require.Contains(t, res.Message, "invalid comparison")
+}
@@ -0,0 +1,218 @@
+package specform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Markdown assertions parse the output as GitHub flavored markdown and check
+// its structure, e.g. `has-heading: "## Summary"`, `list-item-count: ">= 3"`,
+// `has-table: 3` or `max-heading-depth: 3`.
+
+var markdownParser = goldmark.New(goldmark.WithExtensions(extension.Table)).Parser()
+
+// markdownHeading is a heading of the output.
+type markdownHeading struct {
+	level int
+	text  string
+	span  types.Span
+}
+
+// markdownDoc is the structure of a markdown output.
+type markdownDoc struct {
+	headings []markdownHeading
+	lists    []int // number of items of each list, nested lists included
+	tables   []int // number of columns of each table
+}
+
+// parseMarkdown collects the headings, lists and tables of the output.
+func parseMarkdown(output string) markdownDoc {
+	source := []byte(output)
+	root := markdownParser.Parse(text.NewReader(source))
+
+	var doc markdownDoc
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Heading:
+			h := markdownHeading{level: n.Level, text: strings.TrimSpace(markdownText(n, source))}
+			if lines := n.Lines(); lines.Len() > 0 {
+				h.span = types.Span{Start: lines.At(0).Start, End: lines.At(lines.Len() - 1).Stop}
+			}
+			doc.headings = append(doc.headings, h)
+		case *ast.List:
+			doc.lists = append(doc.lists, n.ChildCount())
+		case *east.Table:
+			doc.tables = append(doc.tables, len(n.Alignments))
+		}
+		return ast.WalkContinue, nil
+	})
+	return doc
+}
+
+// markdownText returns the text of an inline container without markup.
+func markdownText(n ast.Node, source []byte) string {
+	var sb strings.Builder
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch c := c.(type) {
+		case *ast.Text:
+			sb.Write(c.Segment.Value(source))
+			if c.SoftLineBreak() || c.HardLineBreak() {
+				sb.WriteByte(' ')
+			}
+		case *ast.String:
+			sb.Write(c.Value)
+		default:
+			sb.WriteString(markdownText(c, source))
+		}
+	}
+	return sb.String()
+}
+
+// parseHeadingValue splits an optional leading run of # from a heading
+// value, e.g. "## Summary" into level 2 and "Summary".
+func parseHeadingValue(value string) (int, string) {
+	value = strings.TrimSpace(value)
+	trimmed := strings.TrimLeft(value, "#")
+	level := len(value) - len(trimmed)
+	if level > 6 || (level > 0 && !strings.HasPrefix(trimmed, " ")) {
+		return 0, value
+	}
+	return level, strings.TrimSpace(trimmed)
+}
+
+// assertHasHeading passes when the output has a heading with the text in
+// the value, compared like contains, and the level if the value starts with
+// #.
+func assertHasHeading(value, output string, _ *types.AssertionContext) types.AssertionResult {
+	level, want := parseHeadingValue(value)
+	doc := parseMarkdown(output)
+
+	result := types.AssertionResult{Type: "has-heading", Value: value, Expected: value}
+	var found []string
+	for _, h := range doc.headings {
+		found = append(found, strings.Repeat("#", h.level)+" "+h.text)
+		if normalizeHeading(h.text) == normalizeHeading(want) && (level == 0 || h.level == level) {
+			result.Passed = true
+			result.Actual = found[len(found)-1]
+			result.Spans = append(result.Spans, h.span)
+		}
+	}
+
+	switch {
+	case result.Passed:
+		result.Message = fmt.Sprintf("✔ Output has heading '%s'", value)
+	case len(found) == 0:
+		result.Message = fmt.Sprintf("✘ Output has no headings, expected '%s'", value)
+	default:
+		result.Actual = strings.Join(found, ", ")
+		result.Message = fmt.Sprintf("✘ Output has no heading '%s', found %s", value, result.Actual)
+	}
+	return result
+}
+
+// normalizeHeading compares headings without case, punctuation or extra
+// whitespace, so "Summary:" matches "summary".
+func normalizeHeading(s string) string {
+	return strings.Join(strings.Fields(normalizeText(s)), " ")
+}
+
+// assertMaxHeadingDepth passes when no heading is deeper than the level in
+// the value, e.g. 3 allows h1 to h3.
+func assertMaxHeadingDepth(value, output string, _ *types.AssertionContext) types.AssertionResult {
+	limit, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(value), "h"))
+	if err != nil || limit < 1 || limit > 6 {
+		return errorResult("max-heading-depth", value, fmt.Sprintf("invalid heading level %q, expected 1 to 6", value))
+	}
+
+	result := types.AssertionResult{Type: "max-heading-depth", Value: value, Expected: fmt.Sprintf("<= %d", limit)}
+	deepest := 0
+	for _, h := range parseMarkdown(output).headings {
+		deepest = max(deepest, h.level)
+		if h.level > limit {
+			result.Spans = append(result.Spans, h.span)
+		}
+	}
+
+	measured := float64(deepest)
+	result.Measured = &measured
+	result.Actual = formatNumber(measured)
+	result.Passed = len(result.Spans) == 0
+	if result.Passed {
+		result.Message = fmt.Sprintf("✔ Deepest heading is h%d (max h%d)", deepest, limit)
+	} else {
+		result.Message = fmt.Sprintf("✘ Deepest heading is h%d, expected at most h%d (%d too deep)", deepest, limit, len(result.Spans))
+	}
+	return result
+}
+
+// assertHasTable passes when the output has a table. A value such as 3 or
+// ">= 2" requires a table whose column count matches.
+func assertHasTable(value, output string, _ *types.AssertionContext) types.AssertionResult {
+	tables := parseMarkdown(output).tables
+	result := types.AssertionResult{Type: "has-table", Value: value}
+	columns := make([]string, len(tables))
+	for i, n := range tables {
+		columns[i] = strconv.Itoa(n)
+	}
+	result.Actual = strings.Join(columns, ", ")
+
+	if strings.TrimSpace(value) == "" {
+		result.Passed = len(tables) > 0
+		result.Message = "✘ Output has no table"
+		if result.Passed {
+			result.Message = fmt.Sprintf("✔ Output has %d table(s)", len(tables))
+		}
+		return result
+	}
+
+	cmp, err := parseComparison(value)
+	if err != nil {
+		return errorResult("has-table", value, err.Error())
+	}
+	result.Expected = cmp.String()
+	for _, n := range tables {
+		if cmp.test(float64(n)) {
+			result.Passed = true
+		}
+	}
+
+	switch {
+	case result.Passed:
+		result.Message = fmt.Sprintf("✔ Output has a table with %s columns", cmp)
+	case len(tables) == 0:
+		result.Message = fmt.Sprintf("✘ Output has no table, expected one with %s columns", cmp)
+	default:
+		result.Message = fmt.Sprintf("✘ Output has tables with %s columns, expected %s", result.Actual, cmp)
+	}
+	return result
+}
+
+// countHeadings counts the markdown headings of the output.
+func countHeadings(s string) int {
+	return len(parseMarkdown(s).headings)
+}
+
+// countLists counts the markdown lists of the output, nested lists included.
+func countLists(s string) int {
+	return len(parseMarkdown(s).lists)
+}
+
+// countListItems counts the items of all markdown lists of the output.
+func countListItems(s string) int {
+	total := 0
+	for _, n := range parseMarkdown(s).lists {
+		total += n
+	}
+	return total
+}
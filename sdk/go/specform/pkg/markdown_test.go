@@ -0,0 +1,61 @@
+package specform
+
+import (
+	"testing"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkdownAssertions(t *testing.T) {
+	output := "# Release notes\n\n## Summary:\n\nShipped **two** fixes.\n\n- Faster sync\n- Fewer retries\n  1. on timeouts\n\n#### Details\n\n| Fix | Ticket |\n| --- | ------ |\n| sync | 12 |\n"
+	results := RunAssertions(output, []types.Assertion{
+		{Type: "has-heading", Value: "summary"},
+		{Type: "has-heading", Value: "## Summary"},
+		{Type: "has-heading", Value: "### Summary"},
+		{Type: "heading-count", Value: "3"},
+		{Type: "max-heading-depth", Value: "3"},
+		{Type: "max-heading-depth", Value: "4"},
+		{Type: "list-count", Value: "2"},
+		{Type: "list-item-count", Value: ">= 3"},
+		{Type: "has-table", Value: ""},
+		{Type: "has-table", Value: "2"},
+		{Type: "has-table", Value: ">= 3"},
+	}, nil)
+
+	require.True(t, results[0].Passed)
+	require.True(t, results[1].Passed)
+	require.Equal(t, "## Summary:", results[1].Actual)
+	require.Equal(t, []types.Span{{Start: 20, End: 28}}, results[1].Spans)
+	require.False(t, results[2].Passed)
+	require.Equal(t, "✘ Output has no heading '### Summary', found # Release notes, ## Summary:, #### Details", results[2].Message)
+
+	require.True(t, results[3].Passed)
+	require.False(t, results[4].Passed)
+	require.Equal(t, 4.0, *results[4].Measured)
+	require.Equal(t, "✘ Deepest heading is h4, expected at most h3 (1 too deep)", results[4].Message)
+	require.True(t, results[5].Passed)
+
+	require.True(t, results[6].Passed)
+	require.True(t, results[7].Passed)
+	require.Equal(t, "3", results[7].Actual)
+
+	require.True(t, results[8].Passed)
+	require.True(t, results[9].Passed)
+	require.False(t, results[10].Passed)
+	require.Equal(t, "✘ Output has tables with 2 columns, expected >= 3", results[10].Message)
+}
+
+func TestMarkdownAssertions_PlainText(t *testing.T) {
+	results := RunAssertions("Just a sentence.", []types.Assertion{
+		{Type: "has-heading", Value: "Summary"},
+		{Type: "has-table", Value: ""},
+		{Type: "max-heading-depth", Value: "2"},
+		{Type: "max-heading-depth", Value: "deep"},
+	}, nil)
+
+	require.Equal(t, "✘ Output has no headings, expected 'Summary'", results[0].Message)
+	require.Equal(t, "✘ Output has no table", results[1].Message)
+	require.True(t, results[2].Passed)
+	require.NotEmpty(t, results[3].Error)
+}
@@ -0,0 +1,72 @@
+package specform
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/specform/specform/sdk/go/specform/internal"
+	"github.com/specform/specform/sdk/go/specform/types"
+)
+
+// Metric assertions measure the output and compare the measurement with
+// the assertion value, e.g. `word-count: "<= 120"` or `bullet-count: 3`.
+
+var bulletPattern = regexp.MustCompile(`^\s*(?:[-*+•]|\d+[.)])\s+\S`)
+
+// countWords counts runs of letters and numbers, see internal.Words.
+func countWords(s string) int {
+	return len(internal.Words(s))
+}
+
+// countChars counts the characters of the output without surrounding
+// whitespace.
+func countChars(s string) int {
+	return utf8.RuneCountInString(strings.TrimSpace(s))
+}
+
+// countSentences counts sentences, see internal.Sentences.
+func countSentences(s string) int {
+	return len(internal.Sentences(s))
+}
+
+// countLines counts lines that are not blank.
+func countLines(s string) int {
+	return len(internal.Lines(s))
+}
+
+// countBullets counts markdown list items, bulleted or numbered.
+func countBullets(s string) int {
+	count := 0
+	for _, line := range strings.Split(s, "\n") {
+		if bulletPattern.MatchString(line) {
+			count++
+		}
+	}
+	return count
+}
+
+// metricAssertion builds an assertion that compares a measurement of the
+// output against the comparison in the assertion value.
+func metricAssertion(name, label string, measure func(string) int) AssertionFn {
+	return func(value, output string, _ *types.AssertionContext) types.AssertionResult {
+		cmp, err := parseComparison(value)
+		if err != nil {
+			return errorResult(name, value, err.Error())
+		}
+
+		measured := float64(measure(output))
+		passed := cmp.test(measured)
+
+		msg := fmt.Sprintf("✔ %s is %s (expected %s)", label, formatNumber(measured), cmp)
+		if !passed {
+			msg = fmt.Sprintf("✘ %s is %s, expected %s", label, formatNumber(measured), cmp)
+			if cmp.op != "!=" {
+				msg += fmt.Sprintf(" (off by %s)", formatNumber(math.Abs(measured-cmp.n)))
+			}
+		}
+		return types.AssertionResult{Type: name, Value: value, Passed: passed, Message: msg, Expected: cmp.String(), Actual: formatNumber(measured), Measured: &measured}
+	}
+}
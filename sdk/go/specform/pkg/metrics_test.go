@@ -0,0 +1,71 @@
+package specform
+
+import (
+	"testing"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricCounts(t *testing.T) {
+	text := `Webhooks push events in real-time. They're cheap to run!
+
+Key points:
+- Retries use back-off
+- Payloads are signed
+1. Verify the signature
+2) Respond within 3.5 seconds`
+
+	require.Equal(t, 27, countWords(text))
+	require.Equal(t, 2, countSentences("Webhooks push events in real-time. They're cheap to run!"))
+	require.Equal(t, 1, countSentences("Respond within 3.5 seconds"))
+	require.Equal(t, 6, countLines(text))
+	require.Equal(t, 4, countBullets(text))
+	require.Equal(t, 5, countChars("  héllo \n"))
+
+	// Expressions count the same way as the metric assertions
+	results := RunAssertions(text, []types.Assertion{
+		{Type: "expr", Value: "len(words(output)) == 27 && len(lines(output)) == 6"},
+		{Type: "expr", Value: `len(sentences("Respond within 3.5 seconds")) == 1`},
+	}, nil)
+	for _, r := range results {
+		require.True(t, r.Passed, r.Message)
+	}
+}
+
+func TestMetricAssertions(t *testing.T) {
+	output := "One. Two. Three."
+
+	tests := []struct {
+		typ      string
+		value    string
+		passed   bool
+		measured float64
+		message  string
+	}{
+		{"word-count", "<= 3", true, 3, "✔ Word count is 3 (expected <= 3)"},
+		{"word-count", "< 2", false, 3, "✘ Word count is 3, expected < 2 (off by 1)"},
+		{"sentence-count", "3", true, 3, ""},
+		{"sentence-count", "!= 3", false, 3, "✘ Sentence count is 3, expected != 3"},
+		{"char-count", ">= 10", true, 16, ""},
+		{"line-count", "== 1", true, 1, ""},
+		{"bullet-count", "≥ 1", false, 0, "off by 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typ+" "+tt.value, func(t *testing.T) {
+			res, err := RunAssertion(output, types.Assertion{Type: tt.typ, Value: tt.value}, nil)
+			require.NoError(t, err)
+			require.Equal(t, tt.passed, res.Passed, res.Message)
+			require.NotNil(t, res.Measured)
+			require.Equal(t, tt.measured, *res.Measured)
+			require.Contains(t, res.Message, tt.message)
+		})
+	}
+
+	res, err := RunAssertion(output, types.Assertion{Type: "word-count", Value: "about 100"}, nil)
+	require.NoError(t, err)
+	require.False(t, res.Passed)
+	require.Nil(t, res.Measured)
+	require.Contains(t, res.Message, "invalid comparison")
+}
@@ -0,0 +1,195 @@
+package specform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+)
+
+// Assertion plugins are executables named specform-assert-<type>. For each
+// assertion the plugin is started with a PluginRequest as JSON on stdin and
+// replies with a PluginResponse as JSON on stdout. A non-zero exit status
+// fails the assertion with an error that includes stderr.
+
+// PluginPrefix is the file name prefix of assertion plugins.
+const PluginPrefix = "specform-assert-"
+
+// PluginProtocolVersion is sent in every request so plugins can reject
+// requests they do not understand.
+const PluginProtocolVersion = 1
+
+// PluginRequest is the JSON a plugin reads from stdin.
+type PluginRequest struct {
+	Version   int               `json:"version"`
+	Type      string            `json:"type"`
+	Value     string            `json:"value"`
+	Output    string            `json:"output"`
+	Inputs    map[string]string `json:"inputs,omitempty"`
+	Reference string            `json:"reference,omitempty"` // known good output, see AssertionContext.Reference
+	Threshold float64           `json:"threshold,omitempty"` // semantic similarity threshold, when set
+}
+
+// PluginResponse is the JSON a plugin writes to stdout. Only passed is
+// required.
+type PluginResponse struct {
+	Passed   bool         `json:"passed"`
+	Message  string       `json:"message,omitempty"`
+	Expected string       `json:"expected,omitempty"`
+	Actual   string       `json:"actual,omitempty"`
+	Measured *float64     `json:"measured,omitempty"`
+	Score    *float64     `json:"score,omitempty"`
+	Spans    []types.Span `json:"spans,omitempty"`
+	Error    string       `json:"error,omitempty"` // set when the plugin could not evaluate the assertion
+}
+
+// PluginAssertion returns an assertion that runs the plugin executable at
+// path. The plugin is killed when the assertion's context is cancelled.
+func PluginAssertion(name, path string) ContextAssertionFn {
+	return func(ctx context.Context, value, output string, actx *types.AssertionContext) types.AssertionResult {
+		req := PluginRequest{Version: PluginProtocolVersion, Type: name, Value: value, Output: output}
+		if actx != nil {
+			req.Inputs = actx.Inputs
+			req.Reference = actx.Reference
+			req.Threshold = actx.Threshold
+		}
+
+		resp, err := runPlugin(ctx, path, req)
+		if err != nil {
+			return errorResult(name, value, fmt.Sprintf("Plugin %s failed: %s", name, err))
+		}
+		if resp.Error != "" {
+			return errorResult(name, value, fmt.Sprintf("Plugin %s: %s", name, resp.Error))
+		}
+
+		msg := resp.Message
+		if msg == "" {
+			msg = passFailMsg(resp.Passed, "Plugin %s passed", "Plugin %s failed", name)
+		} else if !strings.HasPrefix(msg, "✔") && !strings.HasPrefix(msg, "✘") {
+			msg = boolPrefix(resp.Passed) + " " + msg
+		}
+
+		return types.AssertionResult{
+			Type:     name,
+			Value:    value,
+			Passed:   resp.Passed,
+			Message:  msg,
+			Expected: resp.Expected,
+			Actual:   resp.Actual,
+			Measured: resp.Measured,
+			Score:    resp.Score,
+			Spans:    resp.Spans,
+		}
+	}
+}
+
+// runPlugin sends req to the plugin and decodes its response.
+func runPlugin(ctx context.Context, path string, req PluginRequest) (PluginResponse, error) {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return PluginResponse{}, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Don't wait for children of a killed plugin that keep its pipes open
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return PluginResponse{}, ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return PluginResponse{}, fmt.Errorf("%w: %s", err, truncateRunes(msg, 500))
+		}
+		return PluginResponse{}, err
+	}
+
+	var resp PluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return PluginResponse{}, fmt.Errorf("invalid response: %w", err)
+	}
+	return resp, nil
+}
+
+// DiscoverPlugins finds assertion plugins in dirs, mapping each assertion
+// type to its executable. When two directories hold the same plugin, the
+// first one wins. PATH is not searched unless its directories are passed.
+func DiscoverPlugins(dirs []string) map[string]string {
+	plugins := map[string]string{}
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name, ok := pluginName(e.Name())
+			if !ok || plugins[name] != "" {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			if isExecutable(path) {
+				plugins[name] = path
+			}
+		}
+	}
+	return plugins
+}
+
+// RegisterPlugins registers an assertion for every plugin. Assertions that
+// are already registered, such as built-ins, take precedence; the names of
+// the plugins skipped because of them are returned in order.
+func (r *AssertionRegistry) RegisterPlugins(plugins map[string]string) []string {
+	var skipped []string
+	for name, path := range plugins {
+		if err := r.RegisterContext(name, PluginAssertion(name, path)); err != nil {
+			skipped = append(skipped, name)
+		}
+	}
+	sort.Strings(skipped)
+	return skipped
+}
+
+// pluginName returns the assertion type of a plugin file name.
+func pluginName(file string) (string, bool) {
+	if runtime.GOOS == "windows" {
+		file = strings.TrimSuffix(file, filepath.Ext(file))
+	}
+	name, ok := strings.CutPrefix(file, PluginPrefix)
+	return name, ok && name != ""
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(path), ".exe")
+	}
+	return info.Mode()&0111 != 0
+}
+
+// truncateRunes shortens s to at most n runes.
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
@@ -0,0 +1,83 @@
+package specform
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+	"github.com/stretchr/testify/require"
+)
+
+func writePlugin(t *testing.T, dir, name, script string) string {
+	t.Helper()
+	path := filepath.Join(dir, PluginPrefix+name)
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755))
+	return path
+}
+
+func TestPluginAssertions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+
+	dir := t.TempDir()
+	writePlugin(t, dir, "mentions-webhook", `
+cat > "$(dirname "$0")/request.json"
+if grep -q webhook "$(dirname "$0")/request.json"; then
+  echo '{"passed": true, "message": "Mentions webhooks", "score": 0.9}'
+else
+  echo '{"passed": false}'
+fi
+`)
+	writePlugin(t, dir, "crashes", `echo "Traceback: boom" >&2; exit 3`)
+	writePlugin(t, dir, "garbled", `echo "not json"`)
+	writePlugin(t, dir, "sleeps", `sleep 5`)
+	writePlugin(t, dir, "contains", `echo '{"passed": false}'`)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, PluginPrefix+"not-executable"), nil, 0644))
+
+	plugins := DiscoverPlugins([]string{dir})
+	require.NotContains(t, plugins, "not-executable")
+	require.Equal(t, filepath.Join(dir, PluginPrefix+"crashes"), plugins["crashes"])
+
+	registry := DefaultAssertionRegistry().Clone()
+	require.Equal(t, []string{"contains"}, registry.RegisterPlugins(plugins))
+
+	ctx := &types.AssertionContext{Inputs: map[string]string{"topic": "webhooks"}, Timeout: time.Second}
+	results := registry.RunAllContext(context.Background(), "Use a webhook", []types.Assertion{
+		{Type: "mentions-webhook", Value: "strict"},
+		{Type: "not-mentions-webhook"},
+		{Type: "crashes"},
+		{Type: "garbled"},
+		{Type: "sleeps", Timeout: 50 * time.Millisecond},
+		{Type: "contains", Value: "webhook"},
+	}, ctx)
+
+	require.True(t, results[0].Passed)
+	require.Equal(t, "✔ Mentions webhooks", results[0].Message)
+	require.Equal(t, 0.9, *results[0].Score)
+	require.False(t, results[1].Passed)
+	require.Equal(t, "✘ Not: Mentions webhooks", results[1].Message)
+
+	require.Contains(t, results[2].Error, "exit status 3: Traceback: boom")
+	require.Contains(t, results[3].Error, "invalid response")
+	require.Equal(t, "Assertion timed out", results[4].Error)
+
+	// Built-ins take precedence over plugins
+	require.True(t, results[5].Passed)
+
+	data, err := os.ReadFile(filepath.Join(dir, "request.json"))
+	require.NoError(t, err)
+	var req PluginRequest
+	require.NoError(t, json.Unmarshal(data, &req))
+	require.Equal(t, PluginRequest{
+		Version: PluginProtocolVersion,
+		Type:    "mentions-webhook",
+		Output:  "Use a webhook",
+		Inputs:  map[string]string{"topic": "webhooks"},
+	}, req)
+}
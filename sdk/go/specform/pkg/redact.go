@@ -0,0 +1,276 @@
+package specform
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+)
+
+// RedactionRule is a named pattern whose matches are redacted from
+// snapshots and rendered prompts.
+type RedactionRule struct {
+	Name    string `json:"name" yaml:"name"`
+	Pattern string `json:"pattern" yaml:"pattern"`
+
+	// check optionally validates a match, e.g. the Luhn checksum of a card
+	// number, so that unrelated numbers are left alone
+	check func(string) bool
+}
+
+// DefaultRedactionRules returns the built-in rules for email addresses,
+// common API key formats, bearer tokens and card numbers.
+func DefaultRedactionRules() []RedactionRule {
+	return []RedactionRule{
+		{Name: "email", Pattern: `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`},
+		{Name: "api-key", Pattern: `\b(?:sk|pk|rk)[-_](?:live[-_]|test[-_]|proj[-_]|ant[-_])?[A-Za-z0-9_-]{16,}|\bAKIA[0-9A-Z]{16}\b|\bgh[pousr]_[A-Za-z0-9]{36,}\b|\bxox[abprs]-[A-Za-z0-9-]{10,}`},
+		{Name: "bearer-token", Pattern: `(?i)\bbearer\s+[A-Za-z0-9._~+/-]{16,}=*`},
+		{Name: "card-number", Pattern: `\b(?:\d[ -]?){12,18}\d\b`, check: luhnValid},
+	}
+}
+
+// Redactor replaces sensitive values with placeholders such as
+// [REDACTED:email:1f2e3d4c]. Placeholders are derived from a keyed hash of
+// the value, so the same value always gets the same placeholder and
+// snapshot diffs stay meaningful. Without a configured key the public
+// DefaultRedactionKey is used, so anyone can confirm a guess of a short
+// value; configure a project key, or a RandomRedactionKey when placeholders
+// need not survive the process. A Redactor is safe for concurrent use.
+type Redactor struct {
+	rules []compiledRule
+	key   []byte
+}
+
+type compiledRule struct {
+	name  string
+	re    *regexp.Regexp
+	check func(string) bool
+}
+
+// placeholderPattern matches a placeholder produced by a Redactor, so that
+// redacting a snapshot twice leaves it unchanged.
+var placeholderPattern = regexp.MustCompile(`^\[REDACTED:[^:\]]+:[0-9a-f]{8}\]$`)
+
+// minSecretLength is the shortest secret replaced wherever it appears.
+// Shorter secrets, such as "1", would corrupt unrelated text, so they are only
+// redacted where they are a whole input value.
+const minSecretLength = 4
+
+// DefaultRedactionKey derives placeholders for redactors without a
+// configured key. It is fixed so placeholders stay stable across runs.
+var DefaultRedactionKey = []byte("specform-redaction-v1")
+
+// RandomRedactionKey returns a new random key. Placeholders derived from it
+// cannot be checked against guesses, but change with every key.
+func RandomRedactionKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate redaction key: %w", err)
+	}
+	return key, nil
+}
+
+// DefaultRedactor applies DefaultRedactionRules with DefaultRedactionKey.
+var DefaultRedactor = MustNewRedactor(DefaultRedactionRules(), nil)
+
+// SecretsOnlyRedactor has no rules and only redacts the values of inputs
+// marked secret.
+var SecretsOnlyRedactor = &Redactor{}
+
+// NewRedactor compiles a set of redaction rules. The key is used to derive
+// placeholders; when it is empty DefaultRedactionKey is used.
+func NewRedactor(rules []RedactionRule, key []byte) (*Redactor, error) {
+	r := &Redactor{key: key}
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction rule %s: %w", rule.Name, err)
+		}
+		r.rules = append(r.rules, compiledRule{name: rule.Name, re: re, check: rule.check})
+	}
+	return r, nil
+}
+
+// MustNewRedactor is like NewRedactor but panics if a rule does not compile.
+func MustNewRedactor(rules []RedactionRule, key []byte) *Redactor {
+	r, err := NewRedactor(rules, key)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Redact replaces the secret values and every rule match in s with
+// placeholders. Secrets are keyed by a name used in their placeholder.
+func (r *Redactor) Redact(s string, secrets map[string]string) string {
+	if r == nil || s == "" {
+		return s
+	}
+
+	// Replace longer secrets first so a secret containing another is
+	// redacted as a whole
+	names := make([]string, 0, len(secrets))
+	for name, value := range secrets {
+		if len(value) >= minSecretLength && !placeholderPattern.MatchString(value) {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(secrets[names[i]]) != len(secrets[names[j]]) {
+			return len(secrets[names[i]]) > len(secrets[names[j]])
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		s = strings.ReplaceAll(s, secrets[name], r.placeholder(name, secrets[name]))
+	}
+
+	for _, rule := range r.rules {
+		s = rule.re.ReplaceAllStringFunc(s, func(match string) string {
+			if rule.check != nil && !rule.check(match) {
+				return match
+			}
+			return r.placeholder(rule.name, match)
+		})
+	}
+	return s
+}
+
+// RedactSnapshot redacts the output, inputs and every text field of the
+// assertion results of a snapshot in place. Inputs named in secrets are replaced as a whole.
+func (r *Redactor) RedactSnapshot(snapshot *types.Snapshot, secrets map[string]string) {
+	if r == nil {
+		return
+	}
+
+	output := r.Redact(snapshot.Output, secrets)
+	// Spans are offsets into the original output, so they only stay valid
+	// when redaction left the output unchanged
+	keepSpans := output == snapshot.Output
+	snapshot.Output = output
+	snapshot.Inputs = r.RedactInputs(snapshot.Inputs, secrets)
+
+	results := make([]types.AssertionResult, len(snapshot.Assertions))
+	for i, res := range snapshot.Assertions {
+		results[i] = r.redactResult(res, secrets, keepSpans)
+	}
+	snapshot.Assertions = results
+}
+
+// redactResult returns a copy of res with every string field redacted,
+// including those of nested results.
+func (r *Redactor) redactResult(res types.AssertionResult, secrets map[string]string, keepSpans bool) types.AssertionResult {
+	res.Value = r.Redact(res.Value, secrets)
+	res.Message = r.Redact(res.Message, secrets)
+	res.Expected = r.Redact(res.Expected, secrets)
+	res.Actual = r.Redact(res.Actual, secrets)
+	res.Diff = r.Redact(res.Diff, secrets)
+	res.Error = r.Redact(res.Error, secrets)
+	if res.Captures != nil {
+		captures := make(map[string]string, len(res.Captures))
+		for name, v := range res.Captures {
+			captures[name] = r.Redact(v, secrets)
+		}
+		res.Captures = captures
+	}
+	if !keepSpans {
+		res.Spans = nil
+	}
+	if res.Results != nil {
+		// Members of any-of and all-of groups, including negated groups
+		nested := make([]types.AssertionResult, len(res.Results))
+		for i, inner := range res.Results {
+			nested[i] = r.redactResult(inner, secrets, keepSpans)
+		}
+		res.Results = nested
+	}
+	return res
+}
+
+// RedactPrompt returns a copy of a compiled prompt with the defaults of
+// secret inputs replaced as a whole and the prompt text, default inputs and
+// reference output redacted.
+func (r *Redactor) RedactPrompt(prompt *types.CompiledPrompt) *types.CompiledPrompt {
+	if r == nil || prompt == nil {
+		return prompt
+	}
+
+	secrets := SecretInputs(prompt, nil)
+	redacted := *prompt
+	redacted.Prompt = r.Redact(prompt.Prompt, secrets)
+	redacted.Values = r.RedactInputs(prompt.Values, secrets)
+	redacted.Snapshot = r.Redact(prompt.Snapshot, secrets)
+	return &redacted
+}
+
+// RedactInputs returns a redacted copy of inputs. Inputs named in secrets
+// are replaced as a whole.
+func (r *Redactor) RedactInputs(inputs, secrets map[string]string) map[string]string {
+	if r == nil || inputs == nil {
+		return inputs
+	}
+
+	redacted := make(map[string]string, len(inputs))
+	for k, v := range inputs {
+		if _, ok := secrets[k]; ok && v != "" && !placeholderPattern.MatchString(v) {
+			redacted[k] = r.placeholder(k, v)
+			continue
+		}
+		redacted[k] = r.Redact(v, secrets)
+	}
+	return redacted
+}
+
+func (r *Redactor) placeholder(name, value string) string {
+	key := r.key
+	if len(key) == 0 {
+		key = DefaultRedactionKey
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return fmt.Sprintf("[REDACTED:%s:%s]", name, hex.EncodeToString(mac.Sum(nil))[:8])
+}
+
+// SecretInputs returns the values of the inputs a prompt marks as secret,
+// falling back to the prompt's default values.
+func SecretInputs(prompt *types.CompiledPrompt, inputs map[string]string) map[string]string {
+	secrets := map[string]string{}
+	for name, opt := range prompt.InputOptions {
+		if !opt.Secret {
+			continue
+		}
+		if v, ok := inputs[name]; ok {
+			secrets[name] = v
+		} else if v, ok := prompt.Values[name]; ok {
+			secrets[name] = v
+		}
+	}
+	return secrets
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum.
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
@@ -0,0 +1,121 @@
+package specform
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/specform/specform/sdk/go/specform/internal"
+	"github.com/specform/specform/sdk/go/specform/types"
+)
+
+// Regex assertions take a `/pattern/flags` literal, or a bare pattern. The
+// flags i, m, s and U are supported. Patterns are validated when the spec is
+// compiled and compiled once at run time.
+
+var regexCache compileCache[*regexp.Regexp]
+
+// compileRegex returns the compiled regex for a literal, compiling it once.
+func compileRegex(literal string) (*regexp.Regexp, error) {
+	return regexCache.get(literal, internal.CompileRegex)
+}
+
+// regexMatches returns the spans of every match of re and the named
+// captures of the first match.
+func regexMatches(re *regexp.Regexp, output string) ([]types.Span, map[string]string) {
+	var spans []types.Span
+	var captures map[string]string
+	for i, m := range re.FindAllStringSubmatchIndex(output, -1) {
+		spans = append(spans, types.Span{Start: m[0], End: m[1]})
+		if i > 0 {
+			continue
+		}
+		for g, name := range re.SubexpNames() {
+			if name == "" || m[2*g] < 0 {
+				continue
+			}
+			if captures == nil {
+				captures = map[string]string{}
+			}
+			captures[name] = output[m[2*g]:m[2*g+1]]
+		}
+	}
+	return spans, captures
+}
+
+// assertMatches passes when the regex matches the output. Use not-matches
+// for text that must not appear; its spans show where it did.
+func assertMatches(value, output string, _ *types.AssertionContext) types.AssertionResult {
+	re, err := compileRegex(value)
+	if err != nil {
+		return errorResult("matches", value, fmt.Sprintf("Invalid regex: %s", err))
+	}
+
+	result := types.AssertionResult{Type: "matches", Value: value, Expected: value}
+	result.Spans, result.Captures = regexMatches(re, output)
+	result.Passed = len(result.Spans) > 0
+	if result.Passed {
+		result.Actual = output[result.Spans[0].Start:result.Spans[0].End]
+	}
+	result.Message = passFailMsg(result.Passed, "Output matches regex %s", "Output does not match regex %s", value)
+	return result
+}
+
+// assertMatchCount compares the number of matches with a comparison, e.g.
+// `/\bwebhook\b/i >= 2`.
+func assertMatchCount(value, output string, _ *types.AssertionContext) types.AssertionResult {
+	literal, rest := internal.SplitRegexLiteral(value)
+	re, err := compileRegex(literal)
+	if err != nil {
+		return errorResult("match-count", value, fmt.Sprintf("Invalid regex: %s", err))
+	}
+	cmp, err := parseComparison(rest)
+	if err != nil {
+		return errorResult("match-count", value, err.Error())
+	}
+
+	result := types.AssertionResult{Type: "match-count", Value: value, Expected: cmp.String()}
+	result.Spans, result.Captures = regexMatches(re, output)
+
+	measured := float64(len(result.Spans))
+	result.Measured = &measured
+	result.Actual = formatNumber(measured)
+	result.Passed = cmp.test(measured)
+	result.Message = fmt.Sprintf("%s Regex %s matched %s times (expected %s)", boolPrefix(result.Passed), literal, result.Actual, cmp)
+	return result
+}
+
+// assertCaptureEquals checks a named capture group of the first match, e.g.
+// `/Order #(?P<id>\d+)/ id 42`.
+func assertCaptureEquals(value, output string, _ *types.AssertionContext) types.AssertionResult {
+	literal, rest := internal.SplitRegexLiteral(value)
+	re, err := compileRegex(literal)
+	if err != nil {
+		return errorResult("capture-equals", value, fmt.Sprintf("Invalid regex: %s", err))
+	}
+	name, expected, _ := strings.Cut(rest, " ")
+	expected = strings.TrimSpace(expected)
+	if re.SubexpIndex(name) < 0 {
+		return errorResult("capture-equals", value, fmt.Sprintf("Regex %s has no capture group named %q", literal, name))
+	}
+
+	result := types.AssertionResult{Type: "capture-equals", Value: value, Expected: expected}
+	result.Spans, result.Captures = regexMatches(re, output)
+	if len(result.Spans) == 0 {
+		result.Message = fmt.Sprintf("✘ Output does not match regex %s", literal)
+		return result
+	}
+
+	actual, ok := result.Captures[name]
+	result.Actual = actual
+	result.Passed = ok && actual == expected
+	switch {
+	case !ok:
+		result.Message = fmt.Sprintf("✘ Capture %s did not participate in the match", name)
+	case result.Passed:
+		result.Message = fmt.Sprintf("✔ Capture %s is '%s'", name, actual)
+	default:
+		result.Message = fmt.Sprintf("✘ Capture %s is '%s', expected '%s'", name, actual, expected)
+	}
+	return result
+}
@@ -0,0 +1,55 @@
+package specform
+
+import (
+	"testing"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegexAssertions(t *testing.T) {
+	output := "Order #1042 confirmed.\nWebhook sent.\nwebhook retried."
+	results := RunAssertions(output, []types.Assertion{
+		{Type: "matches", Value: `/^webhook sent\.$/im`},
+		{Type: "matches", Value: `/confirmed..webhook/is`},
+		{Type: "not-matches", Value: `/error/i`},
+		{Type: "not-matches", Value: `/retried/`},
+		{Type: "match-count", Value: `/webhook/i >= 2`},
+		{Type: "match-count", Value: `/webhook/ 2`},
+		{Type: "capture-equals", Value: `/Order #(?P<id>\d+)/ id 1042`},
+		{Type: "capture-equals", Value: `/Order #(?P<id>\d+)/ id 7`},
+		{Type: "matches", Value: `/Order #(?P<id>\d+) (?P<status>\w+)/`},
+	}, nil)
+
+	require.True(t, results[0].Passed)
+	require.True(t, results[1].Passed)
+	require.True(t, results[2].Passed)
+
+	require.False(t, results[3].Passed)
+	require.Equal(t, []types.Span{{Start: 45, End: 52}}, results[3].Spans)
+
+	require.True(t, results[4].Passed)
+	require.Equal(t, 2.0, *results[4].Measured)
+	require.Equal(t, "✔ Regex /webhook/i matched 2 times (expected >= 2)", results[4].Message)
+	require.False(t, results[5].Passed)
+	require.Equal(t, "1", results[5].Actual)
+
+	require.True(t, results[6].Passed)
+	require.Equal(t, "✘ Capture id is '1042', expected '7'", results[7].Message)
+	require.Equal(t, map[string]string{"id": "1042", "status": "confirmed"}, results[8].Captures)
+}
+
+func TestRegexAssertions_Errors(t *testing.T) {
+	results := RunAssertions("text", []types.Assertion{
+		{Type: "matches", Value: `/[/`},
+		{Type: "matches", Value: `/text/q`},
+		{Type: "match-count", Value: `/text/ lots`},
+		{Type: "capture-equals", Value: `/(?P<id>\d+)/ total 1`},
+	}, nil)
+
+	for _, r := range results {
+		require.False(t, r.Passed)
+		require.NotEmpty(t, r.Error)
+	}
+	require.Contains(t, results[1].Error, "Invalid regex: unknown flag 'q'")
+}
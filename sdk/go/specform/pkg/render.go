@@ -1,33 +1,241 @@
 package specform
 
 import (
-	"bytes"
+	"context"
 	"fmt"
-	"regexp"
+	"io"
 	"strings"
+	"sync"
 	"text/template"
 
+	"github.com/specform/specform/sdk/go/specform/internal"
 	"github.com/specform/specform/sdk/go/specform/types"
 )
 
 type RenderOptions struct {
-	Strict bool // If true, all variables are to be set
+	Strict         bool         // If true, all variables are to be set
+	Model          string       // Model used to estimate tokens, defaults to the prompt's model
+	MaxInputTokens int          // Overrides the prompt's max_input_tokens budget when set
+	Usage          *RenderUsage // Receives the estimated token counts of the render when set
+
+	// Escapes receives the changes the inputs' escape policies made, in the
+	// same form as RenderTrace.Escapes, when set. RenderBatch ignores it.
+	Escapes *[]types.EscapeTrace
 }
 
-func RenderPrompt(prompt *types.CompiledPrompt, inputs map[string]string, opts *RenderOptions) (string, error) {
-	// Normalize {{var}} → {{.var}} for Go template engine
-	varPattern := regexp.MustCompile(`{{\s*(\w+)\s*}}`)
-	normalizedPrompt := varPattern.ReplaceAllString(prompt.Prompt, "{{.$1}}")
+// RenderUsage holds the estimated token counts of a render. Set
+// RenderOptions.Usage to receive them. RenderBatch ignores it.
+type RenderUsage struct {
+	InputTokens  int // referenced inputs after escaping and truncation
+	PromptTokens int // the rendered prompt
+}
+
+// PreparedPrompt is a compiled prompt whose template has already been parsed.
+// Preparing a prompt once and calling Render repeatedly avoids re-parsing the
+// template on every call. A PreparedPrompt is safe for concurrent use.
+type PreparedPrompt struct {
+	id             string
+	source         string
+	model          string
+	inputs         []string
+	defaults       map[string]string
+	inputOptions   map[string]types.InputOptions
+	maxInputTokens int
+	hasInputLimits bool
+	escapePlans    []*escapePlan
+	tpl            *template.Template
+
+	refsOnce sync.Once
+	refs     []string
+
+	// The trace template is only parsed the first time a trace is requested
+	traceOnce sync.Once
+	traceTpl  *template.Template
+	traceRefs [][]string
+	traceErr  error
+}
+
+// PreparePrompt parses the template of a compiled prompt so it can be rendered
+// many times. The prompt's inputs and default values are copied, so later
+// changes to the compiled prompt do not affect the prepared one.
+func PreparePrompt(prompt *types.CompiledPrompt) (*PreparedPrompt, error) {
+	// Normalize {{var}} → {{.var}} for Go template engine and bind the
+	// registered template functions
+	tpl, err := internal.ParseTemplate("prompt", prompt.Prompt, internal.DefaultFuncs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt template: %w", err)
+	}
+
+	defaults := make(map[string]string, len(prompt.Values))
+	for k, v := range prompt.Values {
+		defaults[k] = v
+	}
+
+	inputOptions := make(map[string]types.InputOptions, len(prompt.InputOptions))
+	hasInputLimits := false
+	for k, v := range prompt.InputOptions {
+		inputOptions[k] = v
+		hasInputLimits = hasInputLimits || v.MaxTokens > 0
+	}
+
+	escapePlans, err := newEscapePlans(prompt.InputOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PreparedPrompt{
+		id:             prompt.ID,
+		source:         prompt.Prompt,
+		model:          prompt.Model,
+		inputs:         append([]string(nil), prompt.Inputs...),
+		defaults:       defaults,
+		inputOptions:   inputOptions,
+		maxInputTokens: prompt.MaxInputTokens,
+		hasInputLimits: hasInputLimits,
+		escapePlans:    escapePlans,
+		tpl:            tpl,
+	}, nil
+}
+
+// ID returns the ID of the compiled prompt this prompt was prepared from.
+func (p *PreparedPrompt) ID() string {
+	return p.id
+}
+
+// Render renders the prepared prompt with the given inputs. Inputs override
+// the default values declared in the spec.
+func (p *PreparedPrompt) Render(inputs map[string]string, opts *RenderOptions) (string, error) {
+	trace := p.reportTrace(opts)
+	merged, err := p.prepareValues(inputs, opts, trace)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.Grow(p.estimateSize(merged))
+	if err := p.execute(context.Background(), &sb, merged); err != nil {
+		return "", err
+	}
+
+	p.report(opts, trace, sb.String())
+	return sb.String(), nil
+}
+
+// RenderTo renders the prepared prompt directly into w, for example an HTTP
+// request body or a file. Rendering stops with ctx's error once ctx is
+// cancelled. Inputs are validated before anything is written, but a failure
+// while executing the template may leave partial output in w.
+func (p *PreparedPrompt) RenderTo(ctx context.Context, w io.Writer, inputs map[string]string, opts *RenderOptions) error {
+	trace := p.reportTrace(opts)
+	merged, err := p.prepareValues(inputs, opts, trace)
+	if err != nil {
+		return err
+	}
+	if trace == nil {
+		return p.execute(ctx, w, merged)
+	}
+
+	// Keep a copy of the output to count its tokens
+	var sb strings.Builder
+	if err := p.execute(ctx, io.MultiWriter(w, &sb), merged); err != nil {
+		return err
+	}
+	p.report(opts, trace, sb.String())
+	return nil
+}
+
+// reportTrace returns a trace to collect token counts and escapes in when
+// the caller asked for them with RenderOptions.Usage or Escapes.
+func (p *PreparedPrompt) reportTrace(opts *RenderOptions) *types.RenderTrace {
+	if opts == nil || (opts.Usage == nil && opts.Escapes == nil) {
+		return nil
+	}
+	return &types.RenderTrace{}
+}
 
-	tpl, err := template.New("prompt").Option("missingkey=error").Parse(normalizedPrompt)
+// report stores the token counts and escapes of a render in the
+// RenderOptions that asked for them. The prompt tokens are counted unless
+// the trace already has them.
+func (p *PreparedPrompt) report(opts *RenderOptions, trace *types.RenderTrace, output string) {
+	if trace == nil || opts == nil {
+		return
+	}
+	if opts.Usage != nil {
+		if trace.PromptTokens == 0 {
+			trace.PromptTokens = CountTokens(output, p.modelFor(opts))
+		}
+		*opts.Usage = RenderUsage{InputTokens: trace.InputTokens, PromptTokens: trace.PromptTokens}
+	}
+	if opts.Escapes != nil {
+		*opts.Escapes = trace.Escapes
+	}
+}
+
+// modelFor returns the model used to estimate tokens.
+func (p *PreparedPrompt) modelFor(opts *RenderOptions) string {
+	if opts != nil && opts.Model != "" {
+		return opts.Model
+	}
+	return p.model
+}
+
+// prepareValues merges the inputs with the defaults and applies the escape
+// policies and token budget, recording changes on trace when it is set.
+func (p *PreparedPrompt) prepareValues(inputs map[string]string, opts *RenderOptions, trace *types.RenderTrace) (map[string]string, error) {
+	merged, err := p.mergeInputs(inputs, opts)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse prompt template: %w", err)
+		return nil, err
+	}
+
+	// Escape first so the budget covers the values as rendered
+	if err := p.applyEscaping(merged, trace); err != nil {
+		return nil, err
+	}
+
+	if err := p.applyBudget(merged, opts, trace); err != nil {
+		return nil, err
+	}
+
+	p.applyDelimiters(merged, trace)
+	return merged, nil
+}
+
+// execute runs the template into w, stopping once ctx is cancelled.
+func (p *PreparedPrompt) execute(ctx context.Context, w io.Writer, values map[string]string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if ctx.Done() != nil {
+		w = &contextWriter{ctx: ctx, w: w}
 	}
 
+	if err := p.tpl.Execute(w, values); err != nil {
+		return fmt.Errorf("failed to renderprompt: %w", err)
+	}
+	return nil
+}
+
+// contextWriter fails writes once its context is cancelled, which stops the
+// template from executing any further.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (c *contextWriter) Write(b []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.w.Write(b)
+}
+
+// mergeInputs layers the caller's inputs over the prompt defaults and, in
+// strict mode, checks that every declared input has a value.
+func (p *PreparedPrompt) mergeInputs(inputs map[string]string, opts *RenderOptions) (map[string]string, error) {
 	// Set default inputs from the scenario if they are not overridden
 	// by the user
-	merged := map[string]string{}
-	for k, v := range prompt.Values {
+	merged := make(map[string]string, len(p.defaults)+len(inputs))
+	for k, v := range p.defaults {
 		merged[k] = v
 	}
 	// Merge user inputs into the merged map
@@ -39,21 +247,55 @@ func RenderPrompt(prompt *types.CompiledPrompt, inputs map[string]string, opts *
 	// Validate that all require inputs are set (strict mode)
 	if opts != nil && opts.Strict {
 		missing := []string{}
-		for _, input := range prompt.Inputs {
+		for _, input := range p.inputs {
 			if _, ok := merged[input]; !ok {
 				missing = append(missing, input)
 			}
 		}
 
 		if len(missing) > 0 {
-			return "", fmt.Errorf("missing required inputs: %s", strings.Join(missing, ", "))
+			return nil, fmt.Errorf("missing required inputs: %s", strings.Join(missing, ", "))
 		}
 	}
 
-	var buf bytes.Buffer
-	if err := tpl.Execute(&buf, merged); err != nil {
-		return "", fmt.Errorf("failed to renderprompt: %w", err)
+	return merged, nil
+}
+
+// inputRefs returns the inputs referenced by the prompt template.
+func (p *PreparedPrompt) inputRefs() []string {
+	p.refsOnce.Do(func() {
+		p.refs = internal.TemplateRefs(p.source, internal.DefaultFuncs)
+	})
+	return p.refs
+}
+
+// estimateSize returns an upper bound guess of the rendered prompt size so the
+// output buffer is allocated once in the common case.
+func (p *PreparedPrompt) estimateSize(values map[string]string) int {
+	size := len(p.source)
+	for _, v := range values {
+		size += len(v)
 	}
+	return size
+}
 
-	return buf.String(), nil
+// RenderTo renders a compiled prompt directly into w. See
+// PreparedPrompt.RenderTo for details.
+func RenderTo(ctx context.Context, w io.Writer, prompt *types.CompiledPrompt, inputs map[string]string, opts *RenderOptions) error {
+	prepared, err := PreparePrompt(prompt)
+	if err != nil {
+		return err
+	}
+	return prepared.RenderTo(ctx, w, inputs, opts)
+}
+
+// RenderPrompt renders a compiled prompt with the given inputs. It parses the
+// prompt template on every call; use PreparePrompt when rendering the same
+// prompt repeatedly.
+func RenderPrompt(prompt *types.CompiledPrompt, inputs map[string]string, opts *RenderOptions) (string, error) {
+	prepared, err := PreparePrompt(prompt)
+	if err != nil {
+		return "", err
+	}
+	return prepared.Render(inputs, opts)
 }
@@ -1,6 +1,11 @@
 package specform
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/specform/specform/sdk/go/specform/types"
@@ -41,3 +46,212 @@ func TestRenderCompiledPrompt_MissingInput(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "missing required inputs: tone")
 }
+
+func TestPreparedPrompt_RenderConcurrent(t *testing.T) {
+	scenario := &types.CompiledPrompt{
+		ID:     "test-scenario",
+		Prompt: "Summarize this: {{article}} using a {{ tone }} tone.",
+		Inputs: []string{"article", "tone"},
+		Values: map[string]string{"tone": "casual"},
+	}
+
+	prepared, err := PreparePrompt(scenario)
+	require.NoError(t, err)
+	require.Equal(t, "test-scenario", prepared.ID())
+
+	// Changing the compiled prompt after preparing must not leak into renders
+	scenario.Values["tone"] = "formal"
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			article := fmt.Sprintf("article %d", i)
+			out, err := prepared.Render(map[string]string{"article": article}, &RenderOptions{Strict: true})
+			if err != nil {
+				errs <- err
+				return
+			}
+			if out != "Summarize this: "+article+" using a casual tone." {
+				errs <- fmt.Errorf("unexpected output: %q", out)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
+
+func TestPreparePrompt_InvalidTemplate(t *testing.T) {
+	_, err := PreparePrompt(&types.CompiledPrompt{Prompt: "Hello {{name"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to parse prompt template")
+}
+
+var benchPrompt = &types.CompiledPrompt{
+	ID:     "bench",
+	Prompt: "Please summarize this article: {{article}}\nMake sure to use a {{tone}} tone.",
+	Inputs: []string{"article", "tone"},
+	Values: map[string]string{"tone": "casual"},
+}
+
+var benchInputs = map[string]string{
+	"article": strings.Repeat("Webhooks enable real-time communication between systems. ", 20),
+}
+
+func BenchmarkRenderPrompt(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := RenderPrompt(benchPrompt, benchInputs, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPreparedPrompt_Render(b *testing.B) {
+	prepared, err := PreparePrompt(benchPrompt)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := prepared.Render(benchInputs, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPreparedPrompt_RenderParallel(b *testing.B) {
+	prepared, err := PreparePrompt(benchPrompt)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := prepared.Render(benchInputs, nil); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func TestRenderPrompt_TemplateFuncs(t *testing.T) {
+	require.NoError(t, RegisterTemplateFunc("shout", func(s string) string { return strings.ToUpper(s) + "!" }))
+	t.Cleanup(func() { require.NoError(t, UnregisterTemplateFunc("shout")) })
+	require.True(t, HasTemplateFunc("shout"))
+
+	scenario := &types.CompiledPrompt{
+		Prompt: "{{shout tone}} {{truncate article 8}}",
+		Inputs: []string{"article", "tone"},
+		Values: map[string]string{"tone": "casual"},
+	}
+
+	out, err := RenderPrompt(scenario, map[string]string{"article": "Webhooks enable real-time communication"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "CASUAL! Webhooks", out)
+}
+
+func TestRenderPrompt_ZeroArgumentTemplateFuncs(t *testing.T) {
+	require.NoError(t, RegisterTemplateFunc("today", func() string { return "2024-05-01" }))
+	t.Cleanup(func() { require.NoError(t, UnregisterTemplateFunc("today")) })
+
+	scenario := &types.CompiledPrompt{
+		Prompt: "As of {{today}}{{if today}} ({{today | upper}}){{end}}, {{json}}",
+		Inputs: []string{"json"},
+	}
+
+	out, trace, err := RenderWithTrace(scenario, map[string]string{"json": "an input"}, &RenderOptions{Strict: true})
+	require.NoError(t, err)
+	require.Equal(t, "As of 2024-05-01 (2024-05-01), an input", out)
+	require.Len(t, trace.Variables, 1)
+	require.Equal(t, "json", trace.Variables[0].Name)
+
+	rendered, err := RenderPrompt(scenario, map[string]string{"json": "an input"}, &RenderOptions{Strict: true})
+	require.NoError(t, err)
+	require.Equal(t, out, rendered)
+}
+
+func TestRenderWithTrace(t *testing.T) {
+	scenario := &types.CompiledPrompt{
+		Prompt: "Summarize: {{article}} in a {{upper tone}} tone. {{if topic}}Topic: {{topic}}{{end}}",
+		Inputs: []string{"article", "tone", "topic"},
+		Values: map[string]string{"tone": "casual", "topic": ""},
+	}
+
+	out, trace, err := RenderWithTrace(scenario, map[string]string{"article": "Webhooks", "extra": "x"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "Summarize: Webhooks in a CASUAL tone. ", out)
+
+	require.Len(t, trace.Variables, 3)
+	require.Equal(t, types.VariableTrace{Name: "article", Source: types.InputSourceInput, Spans: []types.Span{{Start: 11, End: 19}}}, trace.Variables[0])
+	require.Equal(t, types.VariableTrace{Name: "tone", Source: types.InputSourceDefault, Spans: []types.Span{{Start: 25, End: 31}}}, trace.Variables[1])
+	require.Equal(t, "topic", trace.Variables[2].Name)
+	require.Empty(t, trace.Variables[2].Spans)
+	require.Equal(t, []string{"extra"}, trace.UnusedInputs)
+
+	article := trace.Variables[0].Spans[0]
+	require.Equal(t, "Webhooks", out[article.Start:article.End])
+}
+
+func TestRenderWithTrace_Missing(t *testing.T) {
+	scenario := &types.CompiledPrompt{
+		Prompt: "Summarize: {{article}}",
+		Inputs: []string{"article"},
+	}
+
+	_, trace, err := RenderWithTrace(scenario, nil, &RenderOptions{Strict: true})
+	require.Error(t, err)
+	require.NotNil(t, trace)
+	require.Equal(t, types.InputSourceMissing, trace.Variables[0].Source)
+}
+
+func TestRenderTo(t *testing.T) {
+	scenario := &types.CompiledPrompt{
+		Prompt: "Summarize this: {{article}} using a {{tone}} tone.",
+		Inputs: []string{"article", "tone"},
+		Values: map[string]string{"tone": "casual"},
+	}
+
+	var buf bytes.Buffer
+	err := RenderTo(context.Background(), &buf, scenario, map[string]string{"article": "Webhooks"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "Summarize this: Webhooks using a casual tone.", buf.String())
+
+	// Nothing is written when the inputs are invalid
+	buf.Reset()
+	err = RenderTo(context.Background(), &buf, scenario, nil, &RenderOptions{Strict: true})
+	require.Error(t, err)
+	require.Empty(t, buf.String())
+}
+
+func TestRenderTo_Cancelled(t *testing.T) {
+	scenario := &types.CompiledPrompt{Prompt: "{{article}} and {{article}}"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &cancelWriter{cancel: cancel}
+	err := RenderTo(ctx, w, scenario, map[string]string{"article": "Webhooks"}, nil)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, "Webhooks", w.String())
+}
+
+// cancelWriter cancels its context after the first write.
+type cancelWriter struct {
+	bytes.Buffer
+	cancel context.CancelFunc
+}
+
+func (w *cancelWriter) Write(b []byte) (int, error) {
+	defer w.cancel()
+	return w.Buffer.Write(b)
+}
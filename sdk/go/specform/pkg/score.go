@@ -0,0 +1,76 @@
+package specform
+
+import (
+	"context"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+)
+
+// EvaluateAssertions runs the assertions and scores the results. See
+// ScoreResults for how the score and pass are computed.
+func EvaluateAssertions(output string, assertions []types.Assertion, ctx *types.AssertionContext, threshold float64) types.AssertionReport {
+	return defaultRegistry.Evaluate(output, assertions, ctx, threshold)
+}
+
+// EvaluateAssertionsContext is like EvaluateAssertions but stops assertions
+// that are still running once ctx is cancelled.
+func EvaluateAssertionsContext(ctx context.Context, output string, assertions []types.Assertion, actx *types.AssertionContext, threshold float64) types.AssertionReport {
+	return defaultRegistry.EvaluateContext(ctx, output, assertions, actx, threshold)
+}
+
+// Evaluate runs the assertions against this registry and scores the results.
+func (r *AssertionRegistry) Evaluate(output string, assertions []types.Assertion, ctx *types.AssertionContext, threshold float64) types.AssertionReport {
+	return ScoreResults(r.RunAll(output, assertions, ctx), threshold)
+}
+
+// EvaluateContext is like Evaluate but stops assertions that are still
+// running once ctx is cancelled.
+func (r *AssertionRegistry) EvaluateContext(ctx context.Context, output string, assertions []types.Assertion, actx *types.AssertionContext, threshold float64) types.AssertionReport {
+	return ScoreResults(r.RunAllContext(ctx, output, assertions, actx), threshold)
+}
+
+// ScoreResults aggregates assertion results. The score is the weighted
+// share of passed error and warn assertions, from 0 to 1; info assertions
+// are only reported. The report passes when no error assertion failed and
+// the score is at least threshold.
+func ScoreResults(results []types.AssertionResult, threshold float64) types.AssertionReport {
+	report := types.AssertionReport{Results: results, Threshold: threshold, Score: 1}
+
+	total, passed := 0.0, 0.0
+	for _, r := range results {
+		severity := severityOf(r.Severity)
+		if severity == types.SeverityInfo {
+			continue
+		}
+
+		weight := r.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		total += weight
+
+		if r.Passed {
+			passed += weight
+			continue
+		}
+		if severity == types.SeverityWarn {
+			report.Warnings++
+		} else {
+			report.Failed++
+		}
+	}
+
+	if total > 0 {
+		report.Score = passed / total
+	}
+	report.Passed = report.Failed == 0 && report.Score >= threshold
+	return report
+}
+
+// severityOf returns the severity of an assertion, defaulting to error.
+func severityOf(severity string) string {
+	if severity == "" {
+		return types.SeverityError
+	}
+	return severity
+}
@@ -0,0 +1,49 @@
+package specform
+
+import (
+	"testing"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateAssertions(t *testing.T) {
+	output := "Webhooks push events in real time."
+	assertions := []types.Assertion{
+		{Type: "contains", Value: "webhooks", Weight: 3},
+		{Type: "contains", Value: "retries", Severity: types.SeverityWarn},
+		{Type: "contains", Value: "signing", Severity: types.SeverityInfo, Weight: 10},
+	}
+
+	report := EvaluateAssertions(output, assertions, nil, 0)
+	require.True(t, report.Passed)
+	require.InDelta(t, 0.75, report.Score, 1e-9)
+	require.Equal(t, 0, report.Failed)
+	require.Equal(t, 1, report.Warnings)
+	require.Equal(t, types.SeverityWarn, report.Results[1].Severity)
+	require.Equal(t, 3.0, report.Results[0].Weight)
+
+	report = EvaluateAssertions(output, assertions, nil, 0.8)
+	require.False(t, report.Passed)
+
+	// A failed error assertion fails regardless of the score
+	assertions = append(assertions, types.Assertion{Type: "contains", Value: "websocket", Weight: 0.1})
+	report = EvaluateAssertions(output, assertions, nil, 0)
+	require.False(t, report.Passed)
+	require.Equal(t, 1, report.Failed)
+}
+
+func TestScoreResults_Empty(t *testing.T) {
+	report := ScoreResults(nil, 1)
+	require.True(t, report.Passed)
+	require.Equal(t, 1.0, report.Score)
+}
+
+func TestAllAssertionsPassed_IgnoresWarnings(t *testing.T) {
+	require.True(t, allAssertionsPassed([]types.AssertionResult{
+		{Passed: true},
+		{Passed: false, Severity: types.SeverityWarn},
+		{Passed: false, Severity: types.SeverityInfo},
+	}))
+	require.False(t, allAssertionsPassed([]types.AssertionResult{{Passed: false}}))
+}
@@ -1,8 +1,14 @@
 package specform
 
 import (
+	"context"
 	"fmt"
+	"hash/fnv"
 	"math"
+	"strings"
+
+	"github.com/specform/specform/sdk/go/specform/internal"
+	"github.com/specform/specform/sdk/go/specform/types"
 )
 
 // CosineSimilarity returns the cosine similarity between two vectors.
@@ -28,3 +34,142 @@ func CosineSimilarity(a, b []float64) float64 {
 
 	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
 }
+
+// Embedder turns texts into vectors for semantic-similarity. Set it on
+// AssertionContext to use a model-backed embedder.
+type Embedder = types.Embedder
+
+// HashEmbedder is an offline Embedder based on hashed word, word pair and
+// character trigram features. It needs no model or network access. Scores
+// reflect shared vocabulary and word forms rather than meaning, so
+// paraphrases score lower than with a model-backed embedder.
+type HashEmbedder struct {
+	Dims int // vector length, defaults to 1024
+}
+
+// DefaultEmbedder is used by semantic-similarity when the assertion context
+// has no Embedder.
+var DefaultEmbedder Embedder = NewHashEmbedder(1024)
+
+// NewHashEmbedder returns a HashEmbedder producing vectors of dims values.
+func NewHashEmbedder(dims int) *HashEmbedder {
+	return &HashEmbedder{Dims: dims}
+}
+
+// Embed returns one L2-normalized vector per text.
+func (e *HashEmbedder) Embed(_ context.Context, texts []string) ([][]float64, error) {
+	dims := e.Dims
+	if dims <= 0 {
+		dims = 1024
+	}
+
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text, dims)
+	}
+	return vectors, nil
+}
+
+// Feature weights of the hash embedder. Words carry most of the signal,
+// trigrams match different forms of a word and pairs reward shared phrasing.
+const (
+	wordWeight    = 1.0
+	pairWeight    = 0.5
+	trigramWeight = 0.3
+)
+
+func (e *HashEmbedder) embed(text string, dims int) []float64 {
+	counts := map[string]float64{}
+	weights := map[string]float64{}
+	add := func(feature string, weight float64) {
+		counts[feature]++
+		weights[feature] = weight
+	}
+
+	var prev string
+	for _, word := range internal.Words(strings.ToLower(text)) {
+		if stopWords[word] {
+			prev = ""
+			continue
+		}
+		add("w:"+stemWord(word), wordWeight)
+		if prev != "" {
+			add("p:"+prev+" "+word, pairWeight)
+		}
+		prev = word
+
+		padded := []rune("#" + word + "#")
+		for j := 0; j+3 <= len(padded); j++ {
+			add("c:"+string(padded[j:j+3]), trigramWeight)
+		}
+	}
+
+	vec := make([]float64, dims)
+	for feature, count := range counts {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+
+		// The top bit picks the sign so that collisions tend to cancel out
+		sign := 1.0
+		if sum>>63 == 1 {
+			sign = -1.0
+		}
+		vec[sum%uint64(dims)] += sign * (1 + math.Log(count)) * weights[feature]
+	}
+
+	norm := 0.0
+	for _, x := range vec {
+		norm += x * x
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range vec {
+			vec[i] /= norm
+		}
+	}
+	return vec
+}
+
+// stemWord strips common English suffixes so that word forms such as
+// "webhooks" and "webhook" share a feature.
+func stemWord(word string) string {
+	for _, suffix := range []string{"ing", "ed", "es", "s"} {
+		if len(word) > len(suffix)+2 && strings.HasSuffix(word, suffix) {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "has": true, "in": true,
+	"is": true, "it": true, "its": true, "of": true, "on": true, "or": true,
+	"that": true, "the": true, "this": true, "to": true, "was": true,
+	"were": true, "will": true, "with": true,
+}
+
+// semanticScore returns the similarity between value and output, preferring
+// a precomputed score from the context.
+func semanticScore(cctx context.Context, value, output string, ctx *types.AssertionContext) (float64, error) {
+	if ctx != nil && ctx.SemanticScores != nil {
+		if s, ok := ctx.SemanticScores[value]; ok {
+			return s, nil
+		}
+	}
+
+	embedder := DefaultEmbedder
+	if ctx != nil && ctx.Embedder != nil {
+		embedder = ctx.Embedder
+	}
+
+	vectors, err := embedder.Embed(cctx, []string{value, output})
+	if err != nil {
+		return 0, fmt.Errorf("failed to embed: %w", err)
+	}
+	if len(vectors) != 2 || len(vectors[0]) != len(vectors[1]) {
+		return 0, fmt.Errorf("failed to embed: embedder returned mismatched vectors")
+	}
+	return CosineSimilarity(vectors[0], vectors[1]), nil
+}
@@ -0,0 +1,81 @@
+package specform
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/specform/specform/sdk/go/specform/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashEmbedder(t *testing.T) {
+	embedder := NewHashEmbedder(512)
+	vectors, err := embedder.Embed(context.Background(), []string{
+		"Webhooks send real-time HTTP requests between systems.",
+		"A webhook sends an HTTP request in real time.",
+		"The recipe needs two cups of flour and an egg.",
+		"",
+	})
+	require.NoError(t, err)
+	require.Len(t, vectors, 4)
+	require.Len(t, vectors[0], 512)
+
+	related := CosineSimilarity(vectors[0], vectors[1])
+	unrelated := CosineSimilarity(vectors[0], vectors[2])
+	require.Greater(t, related, 0.5)
+	require.Less(t, unrelated, 0.2)
+	require.InDelta(t, 1.0, CosineSimilarity(vectors[0], vectors[0]), 1e-9)
+	require.Zero(t, CosineSimilarity(vectors[0], vectors[3]))
+}
+
+type fixedEmbedder struct {
+	vectors [][]float64
+	err     error
+}
+
+func (e fixedEmbedder) Embed(_ context.Context, texts []string) ([][]float64, error) {
+	return e.vectors, e.err
+}
+
+func TestSemanticSimilarity(t *testing.T) {
+	output := "Webhooks send real-time HTTP requests between systems."
+	assertion := types.Assertion{Type: "semantic-similarity", Value: "webhooks send HTTP requests in real time"}
+
+	// Computed with the default offline embedder
+	res, err := RunAssertion(output, assertion, &types.AssertionContext{Threshold: 0.5})
+	require.NoError(t, err)
+	require.True(t, res.Passed, res.Message)
+	require.NotNil(t, res.Score)
+
+	// Precomputed scores take precedence
+	res, err = RunAssertion(output, assertion, &types.AssertionContext{
+		SemanticScores: map[string]float64{assertion.Value: 0.1},
+		Threshold:      0.5,
+	})
+	require.NoError(t, err)
+	require.False(t, res.Passed)
+	require.Equal(t, 0.1, *res.Score)
+
+	// A custom embedder is used when set
+	res, err = RunAssertion(output, assertion, &types.AssertionContext{
+		Embedder: fixedEmbedder{vectors: [][]float64{{1, 0}, {1, 0}}},
+	})
+	require.NoError(t, err)
+	require.True(t, res.Passed)
+	require.Equal(t, 1.0, *res.Score)
+
+	res, err = RunAssertion(output, assertion, &types.AssertionContext{
+		Embedder: fixedEmbedder{err: errors.New("model unavailable")},
+	})
+	require.NoError(t, err)
+	require.False(t, res.Passed)
+	require.Contains(t, res.Message, "model unavailable")
+
+	res, err = RunAssertion(output, assertion, &types.AssertionContext{
+		Embedder: fixedEmbedder{vectors: [][]float64{{1, 0}, {1}}},
+	})
+	require.NoError(t, err)
+	require.False(t, res.Passed)
+	require.Contains(t, res.Message, "mismatched vectors")
+}
@@ -9,23 +9,51 @@ import (
 	"github.com/specform/specform/sdk/go/specform/types"
 )
 
+// SnapshotOptions configures SaveSnapshotWithOptions.
+type SnapshotOptions struct {
+	// Redactor redacts secrets before the snapshot is written. Defaults to
+	// SecretsOnlyRedactor; use DefaultRedactor to apply the pattern rules.
+	Redactor *Redactor
+}
+
+// SaveSnapshot writes a snapshot. Inputs the prompt marks as secret are
+// replaced with placeholders; use SaveSnapshotWithOptions to apply
+// redaction rules as well.
 func SaveSnapshot(
 	path string,
 	scenario *types.CompiledPrompt,
 	output string,
 	results []types.AssertionResult,
 	inputs map[string]string,
+) error {
+	return SaveSnapshotWithOptions(path, scenario, output, results, inputs, nil)
+}
+
+// SaveSnapshotWithOptions writes a snapshot using the given options.
+func SaveSnapshotWithOptions(
+	path string,
+	scenario *types.CompiledPrompt,
+	output string,
+	results []types.AssertionResult,
+	inputs map[string]string,
+	opts *SnapshotOptions,
 ) error {
 	snapshot := types.Snapshot{
 		ID:         scenario.ID,
 		Hash:       scenario.Hash,
 		Output:     output,
 		Inputs:     inputs,
-		Assertions: results,
+		Assertions: withoutDurations(results),
 		Passed:     allAssertionsPassed(results),
 		Timestamp:  time.Now(),
 	}
 
+	redactor := SecretsOnlyRedactor
+	if opts != nil && opts.Redactor != nil {
+		redactor = opts.Redactor
+	}
+	redactor.RedactSnapshot(&snapshot, SecretInputs(scenario, inputs))
+
 	file, err := os.Create(path)
 	if err != nil {
 		return fmt.Errorf("failed to create snapshot file: %w", err)
@@ -56,9 +84,26 @@ func LoadSnapshot(path string) (*types.Snapshot, error) {
 	return &snapshot, nil
 }
 
+// withoutDurations returns a copy of results with their durations zeroed,
+// so that snapshots of the same results are byte-for-byte identical.
+func withoutDurations(results []types.AssertionResult) []types.AssertionResult {
+	if results == nil {
+		return nil
+	}
+	stable := make([]types.AssertionResult, len(results))
+	for i, res := range results {
+		res.Duration = 0
+		res.Results = withoutDurations(res.Results)
+		stable[i] = res
+	}
+	return stable
+}
+
+// allAssertionsPassed reports whether every error severity assertion
+// passed. Failed warnings and info assertions do not fail a snapshot.
 func allAssertionsPassed(results []types.AssertionResult) bool {
 	for _, result := range results {
-		if !result.Passed {
+		if !result.Passed && severityOf(result.Severity) == types.SeverityError {
 			return false
 		}
 	}
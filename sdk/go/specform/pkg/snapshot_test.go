@@ -44,3 +44,21 @@ func TestSaveSnapshot(t *testing.T) {
 	require.True(t, loaded.Passed)
 	require.Len(t, loaded.Assertions, 2)
 }
+
+func TestSaveSnapshot_OmitsDurations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "durations.snap.json")
+	results := RunAssertions("webhook", []types.Assertion{
+		{Type: "contains", Value: "webhook"},
+		{Type: types.AssertionAllOf, Assertions: []types.Assertion{{Type: "contains", Value: "hook"}}},
+	}, nil)
+	require.Positive(t, results[0].Duration)
+
+	err := SaveSnapshot(path, &types.CompiledPrompt{ID: "durations"}, "webhook", results, nil)
+	require.NoError(t, err)
+
+	loaded, err := LoadSnapshot(path)
+	require.NoError(t, err)
+	require.Zero(t, loaded.Assertions[0].Duration)
+	require.Zero(t, loaded.Assertions[1].Results[0].Duration)
+	require.Positive(t, results[0].Duration, "the caller's results are left untouched")
+}
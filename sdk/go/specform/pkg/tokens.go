@@ -0,0 +1,150 @@
+package specform
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer splits text into approximate model tokens. The built-in
+// tokenizers are estimates tuned per model family; they need no vocabulary
+// files or network access and are meant for budgeting, not billing.
+type Tokenizer interface {
+	// Encode splits text into token sized pieces. Joining the pieces yields
+	// the original text.
+	Encode(text string) []string
+	// Count returns the number of tokens in text.
+	Count(text string) int
+}
+
+// pretokenPattern splits text into words, numbers, punctuation runs and
+// whitespace, similar to the pre-tokenizers of BPE based models.
+var pretokenPattern = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)| ?\pL+| ?\pN+| ?[^\s\pL\pN]+|\s+`)
+
+// estimator is a Tokenizer that approximates a model family's tokenizer
+// from average characters per token.
+type estimator struct {
+	wordChars      int     // latin script words up to this length are a single token
+	charsPerToken  float64 // letters per token in longer words
+	digitsPerToken int     // digits grouped into a single token
+}
+
+// tokenizerFamilies maps model name prefixes to tokenizer estimates.
+var tokenizerFamilies = []struct {
+	prefix string
+	est    *estimator
+}{
+	{"gpt-4o", &estimator{wordChars: 7, charsPerToken: 4.4, digitsPerToken: 3}},
+	{"gpt-4.1", &estimator{wordChars: 7, charsPerToken: 4.4, digitsPerToken: 3}},
+	{"o1", &estimator{wordChars: 7, charsPerToken: 4.4, digitsPerToken: 3}},
+	{"o3", &estimator{wordChars: 7, charsPerToken: 4.4, digitsPerToken: 3}},
+	{"o4", &estimator{wordChars: 7, charsPerToken: 4.4, digitsPerToken: 3}},
+	{"gpt-", &estimator{wordChars: 7, charsPerToken: 4.0, digitsPerToken: 3}},
+	{"claude", &estimator{wordChars: 6, charsPerToken: 3.6, digitsPerToken: 3}},
+	{"llama", &estimator{wordChars: 7, charsPerToken: 4.0, digitsPerToken: 3}},
+	{"mistral", &estimator{wordChars: 6, charsPerToken: 3.5, digitsPerToken: 1}},
+	{"mixtral", &estimator{wordChars: 6, charsPerToken: 3.5, digitsPerToken: 1}},
+	{"gemini", &estimator{wordChars: 7, charsPerToken: 4.0, digitsPerToken: 1}},
+	{"gemma", &estimator{wordChars: 7, charsPerToken: 4.0, digitsPerToken: 1}},
+}
+
+// defaultTokenizer is used when the model is unknown.
+var defaultTokenizer = &estimator{wordChars: 7, charsPerToken: 4.0, digitsPerToken: 3}
+
+// TokenizerForModel returns the token estimator for a model, for example
+// "gpt-4o" or "claude-3-5-sonnet". Unknown models use a GPT-4 style estimate.
+func TokenizerForModel(model string) Tokenizer {
+	model = strings.ToLower(strings.TrimSpace(model))
+	for _, f := range tokenizerFamilies {
+		if strings.HasPrefix(model, f.prefix) {
+			return f.est
+		}
+	}
+	return defaultTokenizer
+}
+
+// CountTokens estimates the number of tokens a model will see for text.
+func CountTokens(text, model string) int {
+	return TokenizerForModel(model).Count(text)
+}
+
+func (e *estimator) Count(text string) int {
+	count := 0
+	for _, piece := range pretokenPattern.FindAllString(text, -1) {
+		count += len(e.split(piece))
+	}
+	return count
+}
+
+func (e *estimator) Encode(text string) []string {
+	var tokens []string
+	for _, piece := range pretokenPattern.FindAllString(text, -1) {
+		tokens = append(tokens, e.split(piece)...)
+	}
+	return tokens
+}
+
+// split breaks a single pre-token into estimated tokens.
+func (e *estimator) split(piece string) []string {
+	body := strings.TrimPrefix(piece, " ")
+	r, _ := utf8.DecodeRuneInString(body)
+
+	switch {
+	case body == "" || unicode.IsSpace(r):
+		// Runs of whitespace are usually merged into a single token
+		return chunkRunes(piece, 8)
+	case unicode.IsNumber(r):
+		return chunkRunes(piece, e.digitsPerToken)
+	case unicode.IsLetter(r):
+		if !isLatin(body) {
+			// Ideographic and most non-latin scripts cost about a token per character
+			return chunkRunes(piece, 1)
+		}
+		n := utf8.RuneCountInString(body)
+		if n <= e.wordChars {
+			return []string{piece}
+		}
+		tokens := int(math.Ceil(float64(n) / e.charsPerToken))
+		return chunkRunes(piece, int(math.Ceil(float64(n)/float64(tokens))))
+	default:
+		return chunkRunes(piece, 2)
+	}
+}
+
+// chunkRunes splits s into chunks of at most size runes. A leading space is
+// kept with the first chunk.
+func chunkRunes(s string, size int) []string {
+	if size < 1 {
+		size = 1
+	}
+	runes := []rune(s)
+	start := 0
+	if len(runes) > 1 && runes[0] == ' ' {
+		start = 1
+	}
+
+	var chunks []string
+	for i := start; i < len(runes); i += size {
+		end := min(i+size, len(runes))
+		from := i
+		if from == start {
+			from = 0
+		}
+		chunks = append(chunks, string(runes[from:end]))
+	}
+	if len(chunks) == 0 {
+		chunks = append(chunks, s)
+	}
+	return chunks
+}
+
+func isLatin(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxLatin1 && !unicode.Is(unicode.Latin, r) {
+			return false
+		}
+	}
+	return true
+}
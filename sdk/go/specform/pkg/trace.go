@@ -0,0 +1,104 @@
+package specform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/specform/specform/sdk/go/specform/internal"
+	"github.com/specform/specform/sdk/go/specform/types"
+)
+
+// RenderWithTrace renders a compiled prompt like RenderPrompt and also returns
+// a trace of where each value came from. The trace is returned even when
+// rendering fails, so it can be used to explain the failure.
+func RenderWithTrace(prompt *types.CompiledPrompt, inputs map[string]string, opts *RenderOptions) (string, *types.RenderTrace, error) {
+	prepared, err := PreparePrompt(prompt)
+	if err != nil {
+		return "", nil, err
+	}
+	return prepared.RenderWithTrace(inputs, opts)
+}
+
+// RenderWithTrace renders the prepared prompt and returns a trace recording,
+// for every referenced input, whether its value came from the caller, the
+// spec defaults or was missing, and the byte spans it produced in the output.
+// Caller inputs that the prompt never references are listed as unused.
+func (p *PreparedPrompt) RenderWithTrace(inputs map[string]string, opts *RenderOptions) (string, *types.RenderTrace, error) {
+	p.traceOnce.Do(func() {
+		p.traceTpl, p.traceRefs, p.traceErr = internal.ParseTraceTemplate("prompt", p.source, internal.DefaultFuncs)
+	})
+	if p.traceErr != nil {
+		return "", nil, fmt.Errorf("failed to parse prompt template: %w", p.traceErr)
+	}
+
+	trace, index := p.newTrace(inputs)
+
+	merged, err := p.prepareValues(inputs, opts, trace)
+	if err != nil {
+		return "", trace, err
+	}
+
+	tpl, err := p.traceTpl.Clone()
+	if err != nil {
+		return "", trace, fmt.Errorf("failed to renderprompt: %w", err)
+	}
+
+	var sb strings.Builder
+	sb.Grow(p.estimateSize(merged))
+	tpl.Funcs(template.FuncMap{
+		internal.TraceFunc: func(action int, value any) string {
+			text := fmt.Sprint(value)
+			span := types.Span{Start: sb.Len(), End: sb.Len() + len(text)}
+			for _, ref := range p.traceRefs[action] {
+				v := &trace.Variables[index[ref]]
+				v.Spans = append(v.Spans, span)
+			}
+			return text
+		},
+	})
+
+	if err := tpl.Execute(&sb, merged); err != nil {
+		return "", trace, fmt.Errorf("failed to renderprompt: %w", err)
+	}
+
+	trace.PromptTokens = CountTokens(sb.String(), p.modelFor(opts))
+	p.report(opts, trace, sb.String())
+
+	return sb.String(), trace, nil
+}
+
+// newTrace builds a trace with the source of every referenced input, and an
+// index from input name to its position in the trace.
+func (p *PreparedPrompt) newTrace(inputs map[string]string) (*types.RenderTrace, map[string]int) {
+	trace := &types.RenderTrace{}
+	index := map[string]int{}
+
+	for _, refs := range p.traceRefs {
+		for _, ref := range refs {
+			if _, ok := index[ref]; ok {
+				continue
+			}
+
+			source := types.InputSourceMissing
+			if _, ok := inputs[ref]; ok {
+				source = types.InputSourceInput
+			} else if _, ok := p.defaults[ref]; ok {
+				source = types.InputSourceDefault
+			}
+
+			index[ref] = len(trace.Variables)
+			trace.Variables = append(trace.Variables, types.VariableTrace{Name: ref, Source: source})
+		}
+	}
+
+	for k := range inputs {
+		if _, ok := index[k]; !ok {
+			trace.UnusedInputs = append(trace.UnusedInputs, k)
+		}
+	}
+	sort.Strings(trace.UnusedInputs)
+
+	return trace, index
+}
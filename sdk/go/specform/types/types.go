@@ -1,30 +1,84 @@
 package types
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type Assertion struct {
-	Type  string `json:"type"`
-	Value string `json:"value"`
+	Type       string        `json:"type"`
+	Value      string        `json:"value"`
+	Weight     float64       `json:"weight,omitempty"`     // share of the aggregate score, defaults to 1
+	Severity   string        `json:"severity,omitempty"`   // one of the Severity constants, defaults to error
+	Assertions []Assertion   `json:"assertions,omitempty"` // members of an any-of or all-of group
+	Timeout    time.Duration `json:"timeout,omitempty"`    // fails the assertion when it runs longer, in nanoseconds
 }
 
+// Assertion groups combine the assertions they contain. Any assertion type,
+// including a group, can be negated with the NegatePrefix.
+const (
+	AssertionAnyOf = "any-of"
+	AssertionAllOf = "all-of"
+	NegatePrefix   = "not-"
+)
+
+// Assertion severities. A failing error assertion fails the run, a failing
+// warning lowers the score and info assertions are only reported.
+const (
+	SeverityError = "error"
+	SeverityWarn  = "warn"
+	SeverityInfo  = "info"
+)
+
 type CompiledPrompt struct {
-	ID          string            `json:"id"`
-	Hash        string            `json:"hash"`
-	Feature     string            `json:"feature,omitempty"`
-	Scenario    string            `json:"scenario"`
-	Prompt      string            `json:"compiledPrompt"`
-	Inputs      []string          `json:"inputs"`
-	Values      map[string]string `json:"defaultInputs"`
-	Assertions  []Assertion       `json:"assertions,omitempty"`
-	Snapshot    string            `json:"snapshot,omitempty"`
-	Tags        []string          `json:"tags,omitempty"`
-	Model       string            `json:"model"`
-	Temperature float64           `json:"temperature,omitempty"`
-	CreatedAt   time.Time         `json:"createdAt"`
-	UpdatedAt   time.Time         `json:"updatedAt"`
-	SourcePath  string            `json:"sourcePath,omitempty"`
+	ID             string                  `json:"id"`
+	Hash           string                  `json:"hash"`
+	Feature        string                  `json:"feature,omitempty"`
+	Scenario       string                  `json:"scenario"`
+	Prompt         string                  `json:"compiledPrompt"`
+	Inputs         []string                `json:"inputs"`
+	Values         map[string]string       `json:"defaultInputs"`
+	InputOptions   map[string]InputOptions `json:"inputOptions,omitempty" yaml:"input_options"`
+	MaxInputTokens int                     `json:"maxInputTokens,omitempty" yaml:"max_input_tokens"`
+	Assertions     []Assertion             `json:"assertions,omitempty"`
+	Snapshot       string                  `json:"snapshot,omitempty"`
+	Tags           []string                `json:"tags,omitempty"`
+	Model          string                  `json:"model"`
+	Temperature    float64                 `json:"temperature,omitempty"`
+	CreatedAt      time.Time               `json:"createdAt"`
+	UpdatedAt      time.Time               `json:"updatedAt"`
+	SourcePath     string                  `json:"sourcePath,omitempty"`
 }
 
+// Truncation strategies for inputs that exceed their token budget.
+const (
+	TruncateHead      = "head"       // keep the start of the value
+	TruncateTail      = "tail"       // keep the end of the value
+	TruncateMiddleOut = "middle-out" // keep the start and end, drop the middle
+	TruncateRefuse    = "refuse"     // fail the render instead of truncating
+)
+
+// InputOptions configures how a single input is handled when a prompt is
+// rendered. Options are declared per input under input_options in the spec
+// frontmatter.
+type InputOptions struct {
+	Truncate       string   `json:"truncate,omitempty" yaml:"truncate"`              // one of the Truncate constants
+	MaxTokens      int      `json:"maxTokens,omitempty" yaml:"max_tokens"`           // per-input token limit
+	Escape         []string `json:"escape,omitempty" yaml:"escape"`                  // escape policies, see the Escape constants
+	Delimiter      string   `json:"delimiter,omitempty" yaml:"delimiter"`            // delimiter for the delimit policy, defaults to <name> tags
+	RejectPatterns []string `json:"rejectPatterns,omitempty" yaml:"reject_patterns"` // extra regexes for the reject-injection policy
+	Secret         bool     `json:"secret,omitempty" yaml:"secret"`                  // redact the value from snapshots
+}
+
+// Escape policies for untrusted input values. They are applied in the order
+// strip-control, escape-fences, reject-injection, delimit.
+const (
+	EscapeStripControl    = "strip-control"    // remove control and invisible formatting characters
+	EscapeFences          = "escape-fences"    // escape markdown code fence markers
+	EscapeRejectInjection = "reject-injection" // refuse values matching known prompt-injection patterns
+	EscapeDelimit         = "delimit"          // wrap the value in delimiters it cannot close
+)
+
 type Snapshot struct {
 	ID         string            `json:"id"`
 	Hash       string            `json:"hash"`
@@ -36,14 +90,116 @@ type Snapshot struct {
 }
 
 type AssertionResult struct {
-	Type    string `json:"type"`
-	Value   string `json:"value"`
-	Passed  bool   `json:"passed"`
-	Message string `json:"message"`
+	Type     string            `json:"type"`
+	Value    string            `json:"value"`
+	Passed   bool              `json:"passed"`
+	Message  string            `json:"message"`
+	Expected string            `json:"expected,omitempty"` // what the assertion expected, e.g. "<= 120" or a JSON value
+	Actual   string            `json:"actual,omitempty"`   // what was found in the output
+	Measured *float64          `json:"measured,omitempty"` // value measured by metric assertions such as word-count
+	Score    *float64          `json:"score,omitempty"`    // similarity or judge score between 0 and 1
+	Spans    []Span            `json:"spans,omitempty"`    // where the assertion matched in the output
+	Captures map[string]string `json:"captures,omitempty"` // named regex capture groups of the first match
+	Diff     string            `json:"diff,omitempty"`     // unified diff of the expected and actual output
+	Duration time.Duration     `json:"duration,omitempty"` // time taken to evaluate the assertion, in nanoseconds
+	Weight   float64           `json:"weight,omitempty"`
+	Severity string            `json:"severity,omitempty"`
+	Error    string            `json:"error,omitempty"`   // set when the assertion could not be evaluated, e.g. an invalid regex
+	Results  []AssertionResult `json:"results,omitempty"` // results of the assertions in an any-of or all-of group
+}
+
+// AssertionReport is the outcome of a set of assertions with an aggregate
+// score.
+type AssertionReport struct {
+	Results   []AssertionResult `json:"results"`
+	Score     float64           `json:"score"`     // weighted share of passed error and warn assertions
+	Threshold float64           `json:"threshold"` // minimum score to pass
+	Passed    bool              `json:"passed"`    // no failed errors and the score meets the threshold
+	Failed    int               `json:"failed"`    // failed error assertions
+	Warnings  int               `json:"warnings"`  // failed warn assertions
 }
 
 // AssertionContext holds optional external data used to evaluate advanced assertions.
 type AssertionContext struct {
 	SemanticScores map[string]float64 // expected value → similarity score
 	Threshold      float64            // override threshold (default 0.85)
+	Embedder       Embedder           // computes similarity scores not found in SemanticScores
+	Reference      string             // known good output for lexical similarity, usually CompiledPrompt.Snapshot
+	Inputs         map[string]string  // inputs the output was generated from
+	Secrets        map[string]string  // inputs marked secret, never sent to a judge
+	Judge          JudgeProvider      // grades judge assertions
+	Timeout        time.Duration      // default time limit of each assertion, none when 0
+	Concurrency    int                // number of assertions evaluated at once, sequential when 0 or 1
+}
+
+// Embedder turns texts into vectors for semantic similarity. Implementations
+// may call a remote model; the returned vectors must all have the same length.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// JudgeRequest is what a judge assertion sends to a JudgeProvider.
+type JudgeRequest struct {
+	Rubric string            `json:"rubric"`
+	Output string            `json:"output"`
+	Inputs map[string]string `json:"inputs,omitempty"`
+}
+
+// JudgeVerdict is a JudgeProvider's grade of an output.
+type JudgeVerdict struct {
+	Pass   bool    `json:"pass"`
+	Score  float64 `json:"score"` // between 0 and 1
+	Reason string  `json:"reason"`
+}
+
+// JudgeProvider grades an output against a rubric, usually with another
+// model.
+type JudgeProvider interface {
+	Judge(ctx context.Context, req JudgeRequest) (JudgeVerdict, error)
+}
+
+// Sources of a value in a rendered prompt.
+const (
+	InputSourceDefault = "default" // default value declared in the spec
+	InputSourceInput   = "input"   // value supplied by the caller
+	InputSourceMissing = "missing" // no value available
+)
+
+// RenderTrace explains where the values in a rendered prompt came from.
+type RenderTrace struct {
+	Variables    []VariableTrace   `json:"variables"`
+	UnusedInputs []string          `json:"unusedInputs,omitempty"` // caller inputs the prompt never references
+	InputTokens  int               `json:"inputTokens"`            // estimated tokens of the referenced inputs
+	PromptTokens int               `json:"promptTokens"`           // estimated tokens of the rendered prompt
+	Truncations  []TruncationTrace `json:"truncations,omitempty"`
+	Escapes      []EscapeTrace     `json:"escapes,omitempty"`
+}
+
+// EscapeTrace records an escape policy that changed an input value.
+type EscapeTrace struct {
+	Input  string `json:"input"`
+	Policy string `json:"policy"`
+	Detail string `json:"detail"`
+}
+
+// TruncationTrace records an input that was shortened to fit its token budget.
+type TruncationTrace struct {
+	Input    string `json:"input"`
+	Strategy string `json:"strategy"`
+	Tokens   int    `json:"tokens"` // estimated tokens before truncation
+	Kept     int    `json:"kept"`   // estimated tokens after truncation
+}
+
+// VariableTrace describes a single input referenced by a prompt template.
+type VariableTrace struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`          // one of the InputSource constants
+	Spans  []Span `json:"spans,omitempty"` // output written by actions that reference the input
+}
+
+// Span is a half-open byte range [Start, End) in a rendered prompt or an
+// assertion output.
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
 }